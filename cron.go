@@ -1,7 +1,9 @@
 package unix
 
 import (
-	"os/exec"
+	"context"
+	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -33,7 +35,16 @@ func NewCronJob(command string) CronJob {
 
 // SetCronTZ sets the timezone of the cron daemon to the specified timezone.
 func SetCronTZ(tz string) error {
-	if lines, err := crons(); err != nil {
+	return SetCronTZContext(context.Background(), tz)
+}
+
+// SetCronTZContext sets the timezone of the cron daemon to the specified timezone using ctx.
+func SetCronTZContext(ctx context.Context, tz string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if lines, err := crons(ctx); err != nil {
 		return err
 	} else {
 		var result strings.Builder
@@ -43,8 +54,7 @@ func SetCronTZ(tz string) error {
 				result.WriteString(line + "\n")
 			}
 		}
-		cmd := `echo "` + result.String() + `" | crontab -`
-		return exec.Command("sudo", "bash", "-c", cmd).Run()
+		return writeCrontab(ctx, result.String())
 	}
 }
 
@@ -77,16 +87,30 @@ type CronJob interface {
 	SetMonth(month int) CronJob
 	// SetDayOfWeek sets the day of the week of the cron job.
 	SetDayOfWeek(day Weekday) CronJob
+	// Schedule sets the schedule of the cron job from cron expression (five fields or @alias).
+	// returns error if expression is not valid.
+	Schedule(expr string) (CronJob, error)
 	// Command sets the command to be executed by the cron job.
 	Command(command string) CronJob
 	// Compile compiles the cron job into a cron expression string.
 	Compile() string
 	// Exists checks if the cron job already exists.
 	Exists() (bool, error)
+	// ExistsContext checks if the cron job already exists using ctx.
+	ExistsContext(ctx context.Context) (bool, error)
 	// Install installs the cron job. returns false if cronjob exists.
 	Install() (bool, error)
+	// InstallContext installs the cron job using ctx.
+	InstallContext(ctx context.Context) (bool, error)
+	// Ensure installs the cron job or updates its schedule if changed.
+	// cron daemon restarted only if crontab changed.
+	Ensure() (Result, error)
+	// EnsureContext ensures the cron job using ctx.
+	EnsureContext(ctx context.Context) (Result, error)
 	// Uninstall uninstalls the cron job.
 	Uninstall() error
+	// UninstallContext uninstalls the cron job using ctx.
+	UninstallContext(ctx context.Context) error
 }
 
 type cronDriver struct {
@@ -221,6 +245,78 @@ func (cron *cronDriver) SetDayOfWeek(day Weekday) CronJob {
 	return cron
 }
 
+func (cron *cronDriver) Schedule(expr string) (CronJob, error) {
+	switch expr = strings.TrimSpace(expr); expr {
+	case "@reboot":
+		return cron.AtReboot(), nil
+	case "@yearly", "@annually":
+		return cron.Yearly(), nil
+	case "@monthly":
+		return cron.Monthly(), nil
+	case "@weekly":
+		return cron.Weekly(Sunday), nil
+	case "@daily", "@midnight":
+		return cron.Daily(), nil
+	case "@hourly":
+		return cron.set("0", "*", "*", "*", "*"), nil
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cron, fmt.Errorf("%q is not a valid cron expression", expr)
+	}
+	for i, field := range fields {
+		if !cronFields[i].valid(field) {
+			return cron, fmt.Errorf("%q is not a valid cron expression, invalid %s field", expr, cronFields[i].name)
+		}
+	}
+	return cron.set(fields[0], fields[1], fields[2], fields[3], fields[4]), nil
+}
+
+// cronField represents allowed values of a cron expression field.
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// valid checks field is a list of values, ranges or * with optional steps.
+func (field cronField) valid(value string) bool {
+	isValue := func(v string) bool {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n >= field.min && n <= field.max
+		}
+		return slices.Contains(field.names, strings.ToLower(v))
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		rng, step, hasStep := strings.Cut(part, "/")
+		if n, err := strconv.Atoi(step); hasStep && (err != nil || n < 1) {
+			return false
+		}
+
+		if rng == "*" {
+			continue
+		} else if from, to, isRange := strings.Cut(rng, "-"); isRange {
+			if !isValue(from) || !isValue(to) {
+				return false
+			}
+		} else if !isValue(rng) {
+			return false
+		}
+	}
+	return true
+}
+
 func (cron *cronDriver) Command(command string) CronJob {
 	cron.command = command
 	return cron
@@ -235,7 +331,15 @@ func (cron cronDriver) Compile() string {
 }
 
 func (cron *cronDriver) Exists() (bool, error) {
-	if lines, err := crons(); err != nil {
+	return cron.ExistsContext(context.Background())
+}
+
+func (cron *cronDriver) ExistsContext(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if lines, err := crons(ctx); err != nil {
 		return false, err
 	} else {
 		for _, line := range lines {
@@ -248,7 +352,15 @@ func (cron *cronDriver) Exists() (bool, error) {
 }
 
 func (cron *cronDriver) Install() (bool, error) {
-	if lines, err := crons(); err != nil {
+	return cron.InstallContext(context.Background())
+}
+
+func (cron *cronDriver) InstallContext(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if lines, err := crons(ctx); err != nil {
 		return false, err
 	} else {
 		var result strings.Builder
@@ -265,17 +377,63 @@ func (cron *cronDriver) Install() (bool, error) {
 			result.WriteString(cron.Compile() + "\n")
 		}
 
-		cmd := `echo "` + result.String() + `" | crontab -`
-		if err := eOf(exec.Command("sudo", "bash", "-c", cmd).Run()); err != nil {
+		if err := writeCrontab(ctx, result.String()); err != nil {
 			return false, err
 		}
+		return true, nil
+	}
+}
 
-		return true, eOf(exec.Command("sudo", "systemctl", "restart", "cron").Run())
+func (cron *cronDriver) Ensure() (Result, error) {
+	return cron.EnsureContext(context.Background())
+}
+
+func (cron *cronDriver) EnsureContext(ctx context.Context) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Unchanged, err
+	}
+
+	lines, err := crons(ctx)
+	if err != nil {
+		return Unchanged, err
 	}
+
+	result := cron.compare(lines)
+	if result == Unchanged {
+		return Unchanged, nil
+	}
+
+	if _, err := cron.InstallContext(ctx); err != nil {
+		return Unchanged, err
+	}
+	return result, nil
+}
+
+// compare detects the change needed to install the job into crontab lines.
+func (cron cronDriver) compare(lines []string) Result {
+	result := Created
+	expected := strings.Join(strings.Fields(cron.Compile()), " ")
+	for _, line := range lines {
+		if ok, cmd := cronCommand(line); ok && cmd == cron.command {
+			if strings.Join(strings.Fields(line), " ") == expected {
+				return Unchanged
+			}
+			result = Updated
+		}
+	}
+	return result
 }
 
 func (cron *cronDriver) Uninstall() error {
-	if lines, err := crons(); err != nil {
+	return cron.UninstallContext(context.Background())
+}
+
+func (cron *cronDriver) UninstallContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if lines, err := crons(ctx); err != nil {
 		return err
 	} else {
 		var result strings.Builder
@@ -286,12 +444,6 @@ func (cron *cronDriver) Uninstall() error {
 				result.WriteString(line + "\n")
 			}
 		}
-		cmd := `echo "` + result.String() + `" | crontab -`
-
-		if err := eOf(exec.Command("sudo", "bash", "-c", cmd).Run()); err != nil {
-			return err
-		}
-
-		return eOf(exec.Command("sudo", "systemctl", "restart", "cron").Run())
+		return writeCrontab(ctx, result.String())
 	}
 }
@@ -0,0 +1,129 @@
+package unix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var (
+	// ErrNotRoot returned if root privileges required but process not running as root.
+	ErrNotRoot = errors.New("root privileges required")
+	// ErrPasswordRequired returned if non-interactive escalation requires password.
+	ErrPasswordRequired = errors.New("password required for privilege escalation")
+	// ErrUnitNotFound returned if systemd unit not exists.
+	ErrUnitNotFound = errors.New("unit not found")
+	// ErrNginxInvalid returned if nginx configuration test failed.
+	ErrNginxInvalid = errors.New("invalid nginx configuration")
+	// ErrCrontabMissing returned if user has no crontab.
+	ErrCrontabMissing = errors.New("crontab missing")
+	// ErrUnitFailed returned if systemd unit failed or not became active in time.
+	ErrUnitFailed = errors.New("unit failed")
+	// ErrSiteNotFound returned if nginx site not exists in sites-available.
+	ErrSiteNotFound = errors.New("site not found")
+	// ErrSiteConflict returned if nginx site conflicts with other enabled sites.
+	ErrSiteConflict = errors.New("site conflicts")
+)
+
+// CommandError represents a failed command.
+// matches sentinel errors detected from stderr using errors.Is.
+type CommandError struct {
+	// Command is the executed program (e.g. sudo).
+	Command string
+	// Args is the arguments of program.
+	Args []string
+	// ExitCode is the exit code of program, -1 if program not started or killed.
+	ExitCode int
+	// Stdout is the captured standard output.
+	Stdout string
+	// Stderr is the captured standard error.
+	Stderr string
+	// Err is the underlying execution error.
+	Err error
+}
+
+func (err *CommandError) Error() string {
+	message := strings.TrimSpace(err.Stderr)
+	if message == "" && err.Err != nil {
+		message = err.Err.Error()
+	}
+	return fmt.Sprintf("%s failed with exit code %d: %s",
+		strings.Join(append([]string{err.Command}, err.Args...), " "), err.ExitCode, message)
+}
+
+func (err *CommandError) Unwrap() []error {
+	if kind := err.kind(); kind != nil {
+		return []error{err.Err, kind}
+	}
+	return []error{err.Err}
+}
+
+// kind detects sentinel error of command from stderr.
+func (err *CommandError) kind() error {
+	stderr := strings.ToLower(err.Stderr)
+	switch {
+	case strings.Contains(stderr, "a password is required"):
+		return ErrPasswordRequired
+	case strings.Contains(stderr, "interactive authentication required"),
+		strings.Contains(stderr, "must be root"),
+		strings.Contains(stderr, "must be run as root"),
+		strings.Contains(stderr, "permission denied"),
+		strings.Contains(stderr, "access denied"):
+		return ErrNotRoot
+	case strings.Contains(stderr, "no crontab for"):
+		return ErrCrontabMissing
+	case strings.Contains(stderr, "unit ") &&
+		(strings.Contains(stderr, "not found") ||
+			strings.Contains(stderr, "could not be found") ||
+			strings.Contains(stderr, "does not exist") ||
+			strings.Contains(stderr, "not loaded")):
+		return ErrUnitNotFound
+	}
+	return nil
+}
+
+// run executes command bound to ctx, returns CommandError on failure.
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	_, err := output(ctx, cmd)
+	return err
+}
+
+// output executes command bound to ctx and returns its standard output, returns CommandError on failure.
+// command terminated after CommandTimeout if ctx has no earlier deadline.
+func output(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	if CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, CommandTimeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd, stop := commandContext(ctx, cmd)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if stopErr := stop(); stopErr != nil {
+		err = errors.Join(err, stopErr)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+		result := &CommandError{
+			Command:  cmd.Args[0],
+			Args:     cmd.Args[1:],
+			ExitCode: -1,
+			Stdout:   stdout.String(),
+			Stderr:   stderr.String(),
+			Err:      err,
+		}
+		if exitErr := new(exec.ExitError); errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		}
+		return stdout.Bytes(), result
+	}
+	return stdout.Bytes(), nil
+}
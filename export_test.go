@@ -0,0 +1,63 @@
+package unix
+
+import "time"
+
+// SetNginxDir points nginx sites and passwords directories into dir.
+func SetNginxDir(dir string) {
+	nginxAvailableDir = dir + "/sites-available/"
+	nginxEnabledDir = dir + "/sites-enabled/"
+	nginxPasswordDir = dir + "/htpasswd/"
+}
+
+var (
+	NginxDirectives     = nginxDirectives
+	NginxDefaultListens = nginxDefaultListens
+)
+
+var EnvQuote = envQuote
+
+// EnvContent renders managed environment file content of service.
+func EnvContent(service SystemdService) (string, error) {
+	return service.(*systemdDriver).envContent()
+}
+
+var ResourcesDirectives = Resources.directives
+
+// SocketContent renders unit content of socket.
+func SocketContent(socket SystemdSocket) (string, error) {
+	return socket.(*socketDriver).compile()
+}
+
+// CronCompare detects the change needed to install job into crontab lines.
+func CronCompare(job CronJob, lines ...string) Result {
+	return job.(*cronDriver).compare(lines)
+}
+
+var Apr1Salt = apr1Salt
+
+// SiteContent renders config content of site.
+func SiteContent(site ServerBlock) (string, error) {
+	return site.(*serverBlock).compile()
+}
+
+// ServiceContent renders unit content of service.
+func ServiceContent(service SystemdService) (string, error) {
+	return service.(*systemdDriver).compile()
+}
+
+var WaitActive = waitActive
+
+// SetActivePolling sets unit state poll interval and settle period of WaitActive.
+func SetActivePolling(poll, settle time.Duration) {
+	activePoll, activeSettle = poll, settle
+}
+
+// ParseDBusProperty returns D-Bus name and value of property assignment.
+func ParseDBusProperty(property string) (string, any, error) {
+	value, err := parseDBusProperty(property)
+	return value.Name, value.Value.Value(), err
+}
+
+var DBusError = dbusError
+
+var RemoveSite = removeSite
@@ -0,0 +1,279 @@
+package unix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// FileOptions represents ownership and permission of written file.
+type FileOptions struct {
+	// Mode is the file permission. default to 0644.
+	Mode os.FileMode
+	// Owner is the owner user name or id. default to owner of existing file.
+	Owner string
+	// Group is the owner group name or id. default to group of existing file.
+	Group string
+}
+
+func (options FileOptions) mode() os.FileMode {
+	if options.Mode == 0 {
+		return 0644
+	}
+	return options.Mode.Perm()
+}
+
+// ids resolves owner and group ids of file, -1 means not changed.
+func (options FileOptions) ids(path string) (int, int, error) {
+	uid, gid := -1, -1
+	if info, err := os.Lstat(path); err == nil {
+		uid, gid = fileOwner(info)
+	} else if !os.IsNotExist(err) {
+		return -1, -1, err
+	}
+
+	if options.Owner != "" {
+		if id, err := strconv.Atoi(options.Owner); err == nil {
+			uid = id
+		} else if u, err := user.Lookup(options.Owner); err != nil {
+			return -1, -1, err
+		} else if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return -1, -1, err
+		}
+	}
+
+	if options.Group != "" {
+		if id, err := strconv.Atoi(options.Group); err == nil {
+			gid = id
+		} else if g, err := user.LookupGroup(options.Group); err != nil {
+			return -1, -1, err
+		} else if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return -1, -1, err
+		}
+	}
+	return uid, gid, nil
+}
+
+// WriteFileAtomic writes data to temporary file in the directory of path, syncs and renames it over path.
+// so readers see either old or new content. ownership and SELinux context of existing file preserved.
+// file written using privilege escalation mode if current user not permitted.
+func WriteFileAtomic(path string, data []byte, options FileOptions) error {
+	return WriteFileAtomicContext(context.Background(), path, data, options)
+}
+
+// WriteFileAtomicContext is WriteFileAtomic bound to ctx, escalation commands canceled when ctx done.
+func WriteFileAtomicContext(ctx context.Context, path string, data []byte, options FileOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := writeAtomic(ctx, path, data, options); errors.Is(err, fs.ErrPermission) {
+		return writePrivileged(ctx, path, data, options)
+	} else {
+		return err
+	}
+}
+
+// writeFile writes file atomically with mode.
+func writeFile(ctx context.Context, path string, data []byte, mode os.FileMode) error {
+	return WriteFileAtomicContext(ctx, path, data, FileOptions{Mode: mode})
+}
+
+func writeAtomic(ctx context.Context, path string, data []byte, options FileOptions) error {
+	dir := filepath.Dir(path)
+	if err := mkdirAll(ctx, dir, 0755); err != nil {
+		return err
+	}
+
+	uid, gid, err := options.ids(path)
+	if err != nil {
+		return err
+	}
+
+	label, err := fileLabel(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), options.mode()); err != nil {
+		return err
+	}
+	if (uid != -1 && uid != os.Getuid()) || (gid != -1 && gid != os.Getgid()) {
+		if err := os.Chown(tmp.Name(), uid, gid); err != nil {
+			return err
+		}
+	}
+	if label != nil {
+		if err := setFileLabel(tmp.Name(), label); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// writePrivileged stages file using escalated install and renames it over path.
+func writePrivileged(ctx context.Context, path string, data []byte, options FileOptions) error {
+	uid, gid, err := options.ids(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "unix-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	staged := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	args := []string{"-D", "-m", fmt.Sprintf("%o", options.mode())}
+	if uid != -1 {
+		args = append(args, "-o", strconv.Itoa(uid))
+	}
+	if gid != -1 {
+		args = append(args, "-g", strconv.Itoa(gid))
+	}
+	if err := run(ctx, privileged("install", append(args, tmp.Name(), staged)...)); err != nil {
+		return err
+	}
+
+	if label, _ := fileLabel(path); label != nil {
+		if err := run(ctx, privileged("chcon", "--reference="+path, staged)); err != nil {
+			return err
+		}
+	}
+
+	if err := run(ctx, privileged("mv", "-f", staged, path)); err != nil {
+		return err
+	}
+	return run(ctx, privileged("sync", filepath.Dir(path)))
+}
+
+// syncDir flushes directory entries to make rename durable.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := f.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
+
+// readFile reads file, escalated if current user not permitted.
+func readFile(ctx context.Context, path string) ([]byte, error) {
+	if data, err := os.ReadFile(path); errors.Is(err, fs.ErrPermission) {
+		return output(ctx, privileged("cat", path))
+	} else {
+		return data, err
+	}
+}
+
+// mkdirAll creates directory with parents, escalated if current user not permitted.
+func mkdirAll(ctx context.Context, path string, mode os.FileMode) error {
+	if err := os.MkdirAll(path, mode); errors.Is(err, fs.ErrPermission) {
+		return run(ctx, privileged("install", "-d", "-m", fmt.Sprintf("%o", mode.Perm()), path))
+	} else {
+		return err
+	}
+}
+
+// remove removes file or empty directory, escalated if current user not permitted.
+func remove(ctx context.Context, path string) error {
+	err := os.Remove(path)
+	if !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+
+	if info, err := os.Lstat(path); err == nil && info.IsDir() {
+		return run(ctx, privileged("rmdir", path))
+	}
+	return run(ctx, privileged("rm", "-f", path))
+}
+
+// removeAll removes path and its children, escalated if current user not permitted.
+func removeAll(ctx context.Context, path string) error {
+	if err := os.RemoveAll(path); errors.Is(err, fs.ErrPermission) {
+		return run(ctx, privileged("rm", "-rf", path))
+	} else {
+		return err
+	}
+}
+
+// symlink creates link to target, escalated if current user not permitted.
+func symlink(ctx context.Context, target, link string) error {
+	if err := os.Symlink(target, link); errors.Is(err, fs.ErrPermission) {
+		return run(ctx, privileged("ln", "-s", target, link))
+	} else {
+		return err
+	}
+}
+
+// rename renames path atomically, escalated if current user not permitted.
+func rename(ctx context.Context, from, to string) error {
+	if err := os.Rename(from, to); errors.Is(err, fs.ErrPermission) {
+		return run(ctx, privileged("mv", "-f", "-T", from, to))
+	} else {
+		return err
+	}
+}
+
+// copyFile copies file content with mode, escalated if current user not permitted.
+func copyFile(ctx context.Context, src, dst string, mode os.FileMode) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if errors.Is(err, fs.ErrPermission) {
+		return run(ctx, privileged("install", "-m", fmt.Sprintf("%o", mode.Perm()), src, dst))
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+	return target.Close()
+}
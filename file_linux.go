@@ -0,0 +1,47 @@
+//go:build linux
+
+package unix
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// selinuxXattr is the extended attribute of SELinux security context.
+const selinuxXattr = "security.selinux"
+
+// fileLabel returns SELinux context of file, nil if file not exists or not labeled.
+func fileLabel(path string) ([]byte, error) {
+	size, err := unix.Lgetxattr(path, selinuxXattr, nil)
+	if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENODATA) || errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	label := make([]byte, size)
+	if size, err = unix.Lgetxattr(path, selinuxXattr, label); err != nil {
+		return nil, err
+	}
+	return label[:size], nil
+}
+
+// setFileLabel sets SELinux context of file.
+func setFileLabel(path string, label []byte) error {
+	if err := unix.Lsetxattr(path, selinuxXattr, label, 0); errors.Is(err, unix.ENOTSUP) {
+		return nil
+	} else {
+		return err
+	}
+}
+
+// fileOwner returns owner and group ids of file.
+func fileOwner(info os.FileInfo) (int, int) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(stat.Uid), int(stat.Gid)
+	}
+	return -1, -1
+}
@@ -2,4 +2,9 @@ module github.com/mekramy/unix
 
 go 1.23.4
 
-require golang.org/x/sys v0.28.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/godbus/dbus/v5 v5.2.2
+	golang.org/x/sys v0.28.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,408 @@
+package unix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestStatePath is the path of last applied manifest used by Apply to detect removed cron jobs.
+var ManifestStatePath = "/var/lib/unix/manifest.json"
+
+// Manifest describes services, sites and cron jobs of a host.
+type Manifest struct {
+	Services []ServiceSpec `json:"services,omitempty" yaml:"services,omitempty" toml:"services,omitempty"`
+	Sites    []SiteSpec    `json:"sites,omitempty" yaml:"sites,omitempty" toml:"sites,omitempty"`
+	Jobs     []JobSpec     `json:"jobs,omitempty" yaml:"jobs,omitempty" toml:"jobs,omitempty"`
+}
+
+// ServiceSpec describes a systemd service of manifest.
+type ServiceSpec struct {
+	Name    string `json:"name" yaml:"name" toml:"name"`
+	Root    string `json:"root" yaml:"root" toml:"root"`
+	Command string `json:"command" yaml:"command" toml:"command"`
+	// User installs service in user scope.
+	User     bool              `json:"user,omitempty" yaml:"user,omitempty" toml:"user,omitempty"`
+	Env      map[string]string `json:"env,omitempty" yaml:"env,omitempty" toml:"env,omitempty"`
+	EnvFiles []string          `json:"env_files,omitempty" yaml:"env_files,omitempty" toml:"env_files,omitempty"`
+	// Hardening is the hardening profile of service (baseline or strict).
+	Hardening      string                  `json:"hardening,omitempty" yaml:"hardening,omitempty" toml:"hardening,omitempty"`
+	ReadWritePaths []string                `json:"read_write_paths,omitempty" yaml:"read_write_paths,omitempty" toml:"read_write_paths,omitempty"`
+	Resources      *Resources              `json:"resources,omitempty" yaml:"resources,omitempty" toml:"resources,omitempty"`
+	Dependencies   map[Dependency][]string `json:"dependencies,omitempty" yaml:"dependencies,omitempty" toml:"dependencies,omitempty"`
+	WantedBy       []string                `json:"wanted_by,omitempty" yaml:"wanted_by,omitempty" toml:"wanted_by,omitempty"`
+	// Instances installs service as template unit and scales it to instances starting from base.
+	Instances int `json:"instances,omitempty" yaml:"instances,omitempty" toml:"instances,omitempty"`
+	Base      int `json:"base,omitempty" yaml:"base,omitempty" toml:"base,omitempty"`
+}
+
+// SiteSpec describes an nginx reverse proxy site of manifest.
+// site proxied to socket if set, otherwise to host (default to localhost) and port.
+type SiteSpec struct {
+	Name            string   `json:"name" yaml:"name" toml:"name"`
+	Domains         []string `json:"domains" yaml:"domains" toml:"domains"`
+	Host            string   `json:"host,omitempty" yaml:"host,omitempty" toml:"host,omitempty"`
+	Port            string   `json:"port,omitempty" yaml:"port,omitempty" toml:"port,omitempty"`
+	Socket          string   `json:"socket,omitempty" yaml:"socket,omitempty" toml:"socket,omitempty"`
+	HTTPS           bool     `json:"https,omitempty" yaml:"https,omitempty" toml:"https,omitempty"`
+	RateLimit       string   `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty" toml:"rate_limit,omitempty"`
+	Burst           int      `json:"burst,omitempty" yaml:"burst,omitempty" toml:"burst,omitempty"`
+	ConnLimit       int      `json:"conn_limit,omitempty" yaml:"conn_limit,omitempty" toml:"conn_limit,omitempty"`
+	Allow           []string `json:"allow,omitempty" yaml:"allow,omitempty" toml:"allow,omitempty"`
+	Deny            []string `json:"deny,omitempty" yaml:"deny,omitempty" toml:"deny,omitempty"`
+	BlockUserAgents []string `json:"block_user_agents,omitempty" yaml:"block_user_agents,omitempty" toml:"block_user_agents,omitempty"`
+}
+
+// JobSpec describes a cron job of manifest.
+type JobSpec struct {
+	Command string `json:"command" yaml:"command" toml:"command"`
+	// Schedule is the cron expression (five fields or @alias) of job.
+	Schedule string `json:"schedule" yaml:"schedule" toml:"schedule"`
+}
+
+// Result represents the result of ensuring or applying an entry.
+type Result int
+
+const (
+	Unchanged Result = iota
+	Created
+	Updated
+	Removed
+)
+
+func (result Result) String() string {
+	switch result {
+	case Created:
+		return "created"
+	case Updated:
+		return "updated"
+	case Removed:
+		return "removed"
+	default:
+		return "unchanged"
+	}
+}
+
+// Change represents an applied manifest entry.
+type Change struct {
+	// Kind is the entry kind (service, site or job).
+	Kind string
+	// Name is the service or site name, or job command.
+	Name   string
+	Result Result
+}
+
+// Report represents the changes of applying a manifest.
+type Report struct {
+	Changes []Change
+}
+
+func (report *Report) add(kind, name string, result Result) {
+	report.Changes = append(report.Changes, Change{Kind: kind, Name: name, Result: result})
+}
+
+// LoadManifest reads manifest from json, yaml or toml file detected by extension.
+func LoadManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &manifest)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &manifest)
+	case ".toml":
+		err = toml.Unmarshal(data, &manifest)
+	default:
+		err = fmt.Errorf("unsupported manifest format %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, manifest.validate()
+}
+
+// validate checks required fields of manifest entries.
+func (manifest Manifest) validate() error {
+	errs := make([]error, 0)
+	for i, spec := range manifest.Services {
+		if spec.Name == "" || spec.Root == "" || spec.Command == "" {
+			errs = append(errs, fmt.Errorf("service %d: name, root and command are required", i+1))
+		} else if spec.Hardening != "" && spec.Hardening != "baseline" && spec.Hardening != "strict" {
+			errs = append(errs, fmt.Errorf("%s service: %q is not a valid hardening profile", spec.Name, spec.Hardening))
+		} else if spec.Instances < 0 {
+			errs = append(errs, fmt.Errorf("%s service: %d is not a valid number of instances", spec.Name, spec.Instances))
+		}
+		for dependency := range spec.Dependencies {
+			if !slices.Contains(dependencies, dependency) {
+				errs = append(errs, fmt.Errorf("%s service: %q is not a valid dependency", spec.Name, dependency))
+			}
+		}
+	}
+
+	for i, spec := range manifest.Sites {
+		if spec.Name == "" || len(spec.Domains) == 0 {
+			errs = append(errs, fmt.Errorf("site %d: name and domains are required", i+1))
+		} else if spec.Port == "" && spec.Socket == "" {
+			errs = append(errs, fmt.Errorf("%s site: port or socket is required", spec.Name))
+		}
+	}
+
+	for i, spec := range manifest.Jobs {
+		if spec.Command == "" {
+			errs = append(errs, fmt.Errorf("job %d: command is required", i+1))
+		} else if _, err := spec.job(); err != nil {
+			errs = append(errs, fmt.Errorf("%s job: %w", spec.Command, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Apply reconciles host with manifest.
+// services and sites managed by this package and jobs of last applied manifest not exists in manifest are removed.
+// Apply continues on failure and returns joined errors, state saved only if all entries applied.
+func Apply(manifest Manifest) (Report, error) {
+	return ApplyContext(context.Background(), manifest)
+}
+
+// ApplyContext reconciles host with manifest using ctx.
+func ApplyContext(ctx context.Context, manifest Manifest) (Report, error) {
+	var report Report
+	errs := make([]error, 0)
+	if err := ctx.Err(); err != nil {
+		return report, err
+	} else if err := manifest.validate(); err != nil {
+		return report, err
+	}
+
+	previous, err := manifestState(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	// remove managed entries not exists in manifest first to release domains and ports
+	services, err := managedServices(ctx, previous.Services)
+	if err != nil {
+		return report, err
+	}
+	for _, spec := range services {
+		if !slices.ContainsFunc(manifest.Services, func(s ServiceSpec) bool { return s.Name == spec.Name && s.User == spec.User }) {
+			if err := spec.service().UninstallContext(ctx); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("%s service: %w", spec.Name, err))
+			} else {
+				report.add("service", spec.Name, Removed)
+			}
+		}
+	}
+
+	sites, err := ListSites()
+	if err != nil && !os.IsNotExist(err) {
+		return report, err
+	}
+	for _, site := range sites {
+		if site.Managed && !slices.ContainsFunc(manifest.Sites, func(s SiteSpec) bool { return s.Name == site.Name }) {
+			if err := NewNginxReverseProxy(site.Name, "").UninstallContext(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s site: %w", site.Name, err))
+			} else {
+				report.add("site", site.Name, Removed)
+			}
+		}
+	}
+
+	// cron jobs have no marker, removed jobs detected from last applied manifest
+	for _, spec := range previous.Jobs {
+		if !slices.ContainsFunc(manifest.Jobs, func(j JobSpec) bool { return j.Command == spec.Command }) {
+			if err := NewCronJob(spec.Command).UninstallContext(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s job: %w", spec.Command, err))
+			} else {
+				report.add("job", spec.Command, Removed)
+			}
+		}
+	}
+
+	// install or update entries
+	for _, spec := range manifest.Services {
+		if result, err := spec.apply(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s service: %w", spec.Name, err))
+		} else {
+			report.add("service", spec.Name, result)
+		}
+	}
+
+	for _, spec := range manifest.Sites {
+		if result, err := spec.apply(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s site: %w", spec.Name, err))
+		} else {
+			report.add("site", spec.Name, result)
+		}
+	}
+
+	for _, spec := range manifest.Jobs {
+		if result, err := spec.apply(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s job: %w", spec.Command, err))
+		} else {
+			report.add("job", spec.Command, result)
+		}
+	}
+
+	if len(errs) > 0 {
+		return report, errors.Join(errs...)
+	}
+	return report, saveManifestState(ctx, manifest)
+}
+
+func (spec ServiceSpec) service() SystemdService {
+	service := NewSystemdService(spec.Name, spec.Root, spec.Command)
+	if spec.User {
+		service.UserScope()
+	}
+	for key, value := range spec.Env {
+		service.Env(key, value)
+	}
+	for _, file := range spec.EnvFiles {
+		service.EnvFile(file)
+	}
+	switch spec.Hardening {
+	case "baseline":
+		hardening := BaselineHardening()
+		hardening.ReadWritePaths = spec.ReadWritePaths
+		service.Harden(hardening)
+	case "strict":
+		service.Harden(StrictHardening(spec.ReadWritePaths...))
+	}
+	if spec.Resources != nil {
+		service.Limit(*spec.Resources)
+	}
+	for dependency, units := range spec.Dependencies {
+		service.Depends(dependency, units...)
+	}
+	if len(spec.WantedBy) > 0 {
+		service.WantedBy(spec.WantedBy...)
+	}
+	if spec.Instances > 0 {
+		service.Instanced(spec.Base)
+	}
+	return service
+}
+
+func (spec ServiceSpec) apply(ctx context.Context) (Result, error) {
+	service := spec.service()
+	result, err := service.EnsureContext(ctx)
+	if err != nil {
+		return Unchanged, err
+	}
+
+	if spec.Instances > 0 {
+		if err := service.ScaleContext(ctx, spec.Instances); err != nil {
+			return Unchanged, err
+		}
+	}
+	return result, nil
+}
+
+func (spec SiteSpec) site() ServerBlock {
+	site := NewNginxReverseProxy(spec.Name, spec.Port)
+	host := spec.Host
+	if host == "" {
+		host = "localhost"
+	}
+	if spec.Socket != "" {
+		site.Target(UnixTarget(spec.Socket))
+	} else if spec.HTTPS {
+		site.Target(HTTPSTarget(host, spec.Port))
+	} else {
+		site.Target(TCPTarget(host, spec.Port))
+	}
+	site.Domains(spec.Domains...)
+	if spec.RateLimit != "" {
+		site.RateLimit(spec.RateLimit, spec.Burst)
+	}
+	if spec.ConnLimit > 0 {
+		site.ConnLimit(spec.ConnLimit)
+	}
+	if len(spec.Allow) > 0 {
+		site.Allow(spec.Allow...)
+	}
+	if len(spec.Deny) > 0 {
+		site.Deny(spec.Deny...)
+	}
+	if len(spec.BlockUserAgents) > 0 {
+		site.BlockUserAgents(spec.BlockUserAgents...)
+	}
+	return site
+}
+
+func (spec SiteSpec) apply(ctx context.Context) (Result, error) {
+	return spec.site().EnsureContext(ctx)
+}
+
+func (spec JobSpec) job() (CronJob, error) {
+	return NewCronJob(spec.Command).Schedule(spec.Schedule)
+}
+
+func (spec JobSpec) apply(ctx context.Context) (Result, error) {
+	job, err := spec.job()
+	if err != nil {
+		return Unchanged, err
+	}
+	return job.EnsureContext(ctx)
+}
+
+// managedServices lists services installed by this package in system and user scope,
+// last applied spec of service used if exists so instances are removed too.
+func managedServices(ctx context.Context, previous []ServiceSpec) ([]ServiceSpec, error) {
+	specs := make([]ServiceSpec, 0)
+	for _, user := range []bool{false, true} {
+		services, err := ListServicesContext(ctx, ServiceFilter{Managed: true, User: user})
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, service := range services {
+			spec := ServiceSpec{Name: strings.TrimSuffix(service.Name, "@"), User: user}
+			if spec.Name != service.Name {
+				spec.Instances = 1
+			}
+			if i := slices.IndexFunc(previous, func(s ServiceSpec) bool { return s.Name == spec.Name && s.User == user }); i >= 0 {
+				spec = previous[i]
+			}
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+// manifestState reads last applied manifest.
+func manifestState(ctx context.Context) (Manifest, error) {
+	var manifest Manifest
+	data, err := readFile(ctx, ManifestStatePath)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	} else if err != nil {
+		return manifest, err
+	}
+	return manifest, json.Unmarshal(data, &manifest)
+}
+
+// saveManifestState writes applied manifest.
+func saveManifestState(ctx context.Context, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(ctx, ManifestStatePath, data, 0600)
+}
@@ -1,18 +1,45 @@
 package unix
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
+	"net"
+	"net/url"
 	"os"
-	"os/exec"
+	"os/user"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
+var (
+	nginxAvailableDir = "/etc/nginx/sites-available/"
+	nginxEnabledDir   = "/etc/nginx/sites-enabled/"
+	nginxPasswordDir  = "/etc/nginx/htpasswd/"
+)
+
+// nginxGroups are the groups of nginx workers on common distributions.
+var nginxGroups = []string{"www-data", "nginx", "http"}
+
+// nginxGroup detects the group of nginx workers, empty if not found.
+func nginxGroup() string {
+	for _, group := range nginxGroups {
+		if _, err := user.LookupGroup(group); err == nil {
+			return group
+		}
+	}
+	return ""
+}
+
 func NewNginxReverseProxy(name, port string) ServerBlock {
 	server := new(serverBlock)
 	server.name = name
-	server.port = port
+	server.target = TCPTarget("localhost", port)
 	server.template = NewEngine()
 	server.template.SetTemplate(`
+{http}
 server {
         listen 80;
         listen [::]:80;
@@ -20,7 +47,7 @@ server {
 
         location / {
             client_max_body_size 1M;
-            proxy_pass http://localhost:{port};
+            proxy_pass {upstream};
             proxy_http_version 1.1;
             proxy_set_header Upgrade $http_upgrade;
             proxy_set_header Connection 'upgrade';
@@ -30,26 +57,121 @@ server {
             proxy_set_header X-Forwarded-For $remote_addr;
             proxy_set_header X-Forwarded-Referer $http_referer;
             proxy_cache_bypass $http_upgrade;
+            {location}
         }
 }
 	`)
 	return server
 }
 
+// ProxyTarget represents the upstream of a reverse proxy.
+type ProxyTarget struct {
+	scheme     string
+	host       string
+	port       string
+	socket     string
+	verify     bool
+	ca         string
+	serverName string
+}
+
+// TCPTarget creates an http upstream listening on host and port.
+func TCPTarget(host, port string) ProxyTarget {
+	return ProxyTarget{scheme: "http", host: host, port: port}
+}
+
+// UnixTarget creates an http upstream listening on unix domain socket.
+func UnixTarget(socket string) ProxyTarget {
+	return ProxyTarget{scheme: "http", socket: socket}
+}
+
+// HTTPSTarget creates an https upstream listening on host and port.
+// server name always sent (SNI), upstream certificate is not verified unless Verify called.
+func HTTPSTarget(host, port string) ProxyTarget {
+	return ProxyTarget{scheme: "https", host: host, port: port}
+}
+
+// Verify enables upstream certificate verification using trusted ca file, ca is required.
+// serverName used for verification and SNI, default to target host if empty.
+func (target ProxyTarget) Verify(ca, serverName string) ProxyTarget {
+	target.verify = true
+	target.ca = ca
+	target.serverName = serverName
+	return target
+}
+
+// String returns the proxy_pass address of target.
+func (target ProxyTarget) String() string {
+	if target.socket != "" {
+		return target.scheme + "://unix:" + target.socket
+	}
+	return target.scheme + "://" + net.JoinHostPort(target.host, target.port)
+}
+
+// directives renders proxy ssl directives of target.
+func (target ProxyTarget) directives() ([]string, error) {
+	directives := make([]string, 0)
+	if target.scheme != "https" {
+		return directives, nil
+	}
+
+	directives = append(directives, "proxy_ssl_server_name on;")
+	if !target.verify {
+		return append(directives, "proxy_ssl_verify off;"), nil
+	}
+
+	if target.ca == "" {
+		return nil, fmt.Errorf("%s trusted ca required to verify upstream", target)
+	} else if err := ValidatePath(target.ca); err != nil {
+		return nil, err
+	}
+	directives = append(directives,
+		"proxy_ssl_verify on;",
+		"proxy_ssl_trusted_certificate "+target.ca+";",
+	)
+	if target.serverName != "" {
+		if err := ValidateToken(target.serverName); err != nil {
+			return nil, err
+		}
+		directives = append(directives, "proxy_ssl_name "+target.serverName+";")
+	}
+	return directives, nil
+}
+
 type ServerBlock interface {
 	// Name sets the name of the site.
 	Name(name string) ServerBlock
-	// Port sets the port for the site.
+	// Port sets the localhost port for the site.
 	Port(port string) ServerBlock
+	// Target sets the upstream of the site.
+	Target(target ProxyTarget) ServerBlock
 	// Domains sets the domains for the site.
 	Domains(domains ...string) ServerBlock
+	// RateLimit limits requests per client address.
+	// rate is in nginx format (e.g. 10r/s), burst is the number of queued excessive requests.
+	RateLimit(rate string, burst int) ServerBlock
+	// ConnLimit limits concurrent connections per client address.
+	ConnLimit(connections int) ServerBlock
+	// Allow allows access for the addresses (e.g. 10.0.0.0/8).
+	Allow(addresses ...string) ServerBlock
+	// Deny denies access for the addresses (e.g. all). deny rules applied after allow rules.
+	Deny(addresses ...string) ServerBlock
+	// BasicAuth protects the site with HTTP basic authentication.
+	// users is a map of username to plain password, passwords hashed on install.
+	BasicAuth(realm string, users map[string]string) ServerBlock
+	// BlockUserAgents rejects requests with user agent matching any of case-insensitive patterns.
+	BlockUserAgents(patterns ...string) ServerBlock
 	// Template sets the template for the site.
-	// template string can contain {domains} and {port} placeholders.
+	// template string can contain {domains}, {upstream}, {port}, {http} and {location} placeholders.
 	Template(engine TemplateEngine) ServerBlock
 	// Disable disables the site manually.
 	Disable() error
+	// DisableContext disables the site manually using ctx.
+	DisableContext(ctx context.Context) error
 	// Enable enables the site manually.
 	Enable() error
+	// EnableContext enables the site manually using ctx.
+	EnableContext(ctx context.Context) error
 	// Exists checks if the site exists.
 	Exists() (bool, error)
 	// Enabled checks if the site exists and enabled.
@@ -58,23 +180,140 @@ type ServerBlock interface {
 	// override parameter indicating whether to override existing configurations.
 	// returns false if site exists and not override.
 	Install(override bool) (bool, error)
+	// InstallContext installs the site using ctx.
+	InstallContext(ctx context.Context, override bool) (bool, error)
+	// Ensure installs the site or updates it if rendered content or basic auth users changed.
+	// nginx reloaded only if site changed.
+	Ensure() (Result, error)
+	// EnsureContext ensures the site using ctx.
+	EnsureContext(ctx context.Context) (Result, error)
 	// Uninstall uninstalls the site.
 	Uninstall() error
+	// UninstallContext uninstalls the site using ctx.
+	UninstallContext(ctx context.Context) error
+	// Conflicts checks other enabled sites for domains claimed by this site
+	// and clashing default_server listen directives.
+	Conflicts() ([]string, error)
 }
 
 type serverBlock struct {
-	name     string
-	domains  []string
-	port     string
-	template TemplateEngine
+	name       string
+	domains    []string
+	target     ProxyTarget
+	rate       string
+	burst      int
+	conns      int
+	allow      []string
+	deny       []string
+	realm      string
+	users      map[string]string
+	userAgents []string
+	template   TemplateEngine
 }
 
 func (server serverBlock) path() string {
-	return "/etc/nginx/sites-available/" + server.name
+	return nginxAvailableDir + server.name
 }
 
 func (server serverBlock) link() string {
-	return "/etc/nginx/sites-enabled/" + server.name
+	return nginxEnabledDir + server.name
+}
+
+func (server serverBlock) passwords() string {
+	return nginxPasswordDir + server.name
+}
+
+// zone generates a valid nginx zone name prefix from site name.
+// name hash appended to keep zones of names like a.b and a_b distinct.
+func (server serverBlock) zone() string {
+	hash := fnv.New32a()
+	hash.Write([]byte(server.name))
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, server.name) + fmt.Sprintf("_%08x", hash.Sum32())
+}
+
+// httpDirectives renders directives of http context.
+func (server serverBlock) httpDirectives() (string, error) {
+	if err := ValidateToken(server.rate); err != nil {
+		return "", err
+	}
+
+	directives := make([]string, 0)
+	if len(server.users) > 0 && hasControl(server.realm) {
+		return "", fmt.Errorf("%q is not a valid realm", server.realm)
+	}
+	for user := range server.users {
+		if user == "" || strings.ContainsFunc(user, unicode.IsSpace) || strings.Contains(user, ":") || hasControl(user) {
+			return "", fmt.Errorf("%q is not a valid user name", user)
+		}
+	}
+
+	if server.rate != "" {
+		directives = append(directives, "limit_req_zone $binary_remote_addr zone="+server.zone()+"_req:10m rate="+server.rate+";")
+	}
+	if server.conns > 0 {
+		directives = append(directives, "limit_conn_zone $binary_remote_addr zone="+server.zone()+"_conn:10m;")
+	}
+	return strings.Join(directives, "\n"), nil
+}
+
+// locationDirectives renders directives of location context.
+func (server serverBlock) locationDirectives() (string, error) {
+	directives, err := server.target.directives()
+	if err != nil {
+		return "", err
+	}
+
+	for _, address := range slices.Concat(server.allow, server.deny) {
+		if err := ValidateToken(address); err != nil {
+			return "", err
+		}
+	}
+	for _, pattern := range server.userAgents {
+		if strings.Contains(pattern, `"`) || strings.HasSuffix(pattern, `\`) || hasControl(pattern) {
+			return "", fmt.Errorf("%q is not a valid user agent pattern", pattern)
+		}
+	}
+
+	if len(server.users) > 0 && hasControl(server.realm) {
+		return "", fmt.Errorf("%q is not a valid realm", server.realm)
+	}
+	for user := range server.users {
+		if user == "" || strings.ContainsFunc(user, unicode.IsSpace) || strings.Contains(user, ":") || hasControl(user) {
+			return "", fmt.Errorf("%q is not a valid user name", user)
+		}
+	}
+
+	if server.rate != "" {
+		directives = append(directives, "limit_req zone="+server.zone()+"_req burst="+strconv.Itoa(server.burst)+" nodelay;")
+	}
+	if server.conns > 0 {
+		directives = append(directives, "limit_conn "+server.zone()+"_conn "+strconv.Itoa(server.conns)+";")
+	}
+	for _, address := range server.allow {
+		directives = append(directives, "allow "+address+";")
+	}
+	for _, address := range server.deny {
+		directives = append(directives, "deny "+address+";")
+	}
+	if len(server.users) > 0 {
+		directives = append(directives,
+			`auth_basic "`+strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(server.realm)+`";`,
+			"auth_basic_user_file "+server.passwords()+";",
+		)
+	}
+	if len(server.userAgents) > 0 {
+		directives = append(directives,
+			`if ($http_user_agent ~* "(`+strings.Join(server.userAgents, "|")+`)") {`,
+			"    return 403;",
+			"}",
+		)
+	}
+	return strings.Join(directives, "\n            "), nil
 }
 
 func (server *serverBlock) Name(name string) ServerBlock {
@@ -83,7 +322,12 @@ func (server *serverBlock) Name(name string) ServerBlock {
 }
 
 func (server *serverBlock) Port(port string) ServerBlock {
-	server.port = port
+	server.target = TCPTarget("localhost", port)
+	return server
+}
+
+func (server *serverBlock) Target(target ProxyTarget) ServerBlock {
+	server.target = target
 	return server
 }
 
@@ -92,37 +336,75 @@ func (server *serverBlock) Domains(domains ...string) ServerBlock {
 	return server
 }
 
+func (server *serverBlock) RateLimit(rate string, burst int) ServerBlock {
+	server.rate = rate
+	server.burst = burst
+	return server
+}
+
+func (server *serverBlock) ConnLimit(connections int) ServerBlock {
+	server.conns = connections
+	return server
+}
+
+func (server *serverBlock) Allow(addresses ...string) ServerBlock {
+	server.allow = append(server.allow, addresses...)
+	return server
+}
+
+func (server *serverBlock) Deny(addresses ...string) ServerBlock {
+	server.deny = append(server.deny, addresses...)
+	return server
+}
+
+func (server *serverBlock) BasicAuth(realm string, users map[string]string) ServerBlock {
+	server.realm = realm
+	server.users = users
+	return server
+}
+
+func (server *serverBlock) BlockUserAgents(patterns ...string) ServerBlock {
+	server.userAgents = append(server.userAgents, patterns...)
+	return server
+}
+
 func (server *serverBlock) Template(engine TemplateEngine) ServerBlock {
 	server.template = engine
 	return server
 }
 
 func (server *serverBlock) Disable() error {
-	// delete link
-	if exists, err := FileExists(server.link()); err != nil {
+	return server.DisableContext(context.Background())
+}
+
+func (server *serverBlock) DisableContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
 		return err
-	} else if !exists {
-		return nil
-	} else if err := os.Remove(server.link()); err != nil {
+	}
+
+	if err := disableSite(ctx, server.name); err != nil {
 		return err
 	}
 
 	// Reload nginx to apply the changes
-	return eOf(exec.Command("sudo", "systemctl", "restart", "nginx").Run())
+	return reloadNginx(ctx, "restart")
 }
 
 func (server *serverBlock) Enable() error {
-	// delete link
-	if exists, err := FileExists(server.path()); err != nil {
+	return server.EnableContext(context.Background())
+}
+
+func (server *serverBlock) EnableContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
 		return err
-	} else if !exists {
-		return fmt.Errorf("%s file not exists", server.path())
-	} else if err := os.Symlink(server.path(), server.link()); err != nil {
+	}
+
+	if err := enableSite(ctx, server.name); err != nil {
 		return err
 	}
 
 	// Reload nginx to apply the changes
-	return eOf(exec.Command("sudo", "systemctl", "restart", "nginx").Run())
+	return reloadNginx(ctx, "restart")
 }
 
 func (server *serverBlock) Exists() (bool, error) {
@@ -139,11 +421,78 @@ func (server *serverBlock) Enabled() (bool, error) {
 	}
 }
 
+func (server *serverBlock) compile() (string, error) {
+	http, err := server.httpDirectives()
+	if err != nil {
+		return "", err
+	}
+
+	location, err := server.locationDirectives()
+	if err != nil {
+		return "", err
+	}
+
+	return server.template.
+		AddOptional("upstream", server.target.String(), TokenParam).
+		AddOptional("port", server.target.port, PortParam).
+		AddParameter("domains", strings.Join(server.domains, " "), DomainParam).
+		AddParameter("http", http).
+		AddParameter("location", location).
+		CompileStrict()
+}
+
+func (server *serverBlock) Conflicts() ([]string, error) {
+	entries, err := os.ReadDir(nginxEnabledDir)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := server.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := nginxDirective(content, "server_name")
+	listens := nginxDefaultListens(content)
+	conflicts := make([]string, 0)
+	for _, entry := range entries {
+		if entry.Name() == server.name || entry.IsDir() {
+			continue
+		}
+
+		raw, err := os.ReadFile(nginxEnabledDir + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, domain := range nginxDirective(string(raw), "server_name") {
+			if slices.ContainsFunc(domains, func(name string) bool { return domainsOverlap(name, domain) }) {
+				conflicts = append(conflicts, fmt.Sprintf("%s domain already claimed by %s site", domain, entry.Name()))
+			}
+		}
+
+		for _, listen := range nginxDefaultListens(string(raw)) {
+			if slices.Contains(listens, listen) {
+				conflicts = append(conflicts, fmt.Sprintf("%s default_server already defined by %s site", listen, entry.Name()))
+			}
+		}
+	}
+	return conflicts, nil
+}
+
 func (server *serverBlock) Install(override bool) (bool, error) {
-	content := server.template.
-		AddParameter("port", server.port).
-		AddParameter("domains", strings.Join(server.domains, " ")).
-		Compile()
+	return server.InstallContext(context.Background(), override)
+}
+
+func (server *serverBlock) InstallContext(ctx context.Context, override bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	content, err := server.compile()
+	if err != nil {
+		return false, err
+	}
 
 	if exists, err := FileExists(server.path()); err != nil {
 		return false, err
@@ -151,32 +500,299 @@ func (server *serverBlock) Install(override bool) (bool, error) {
 		return false, nil
 	}
 
-	if err := os.WriteFile(server.path(), []byte(content), 0644); err != nil {
+	if conflicts, err := server.Conflicts(); err != nil {
+		return false, err
+	} else if len(conflicts) > 0 {
+		return false, fmt.Errorf("%w: %s: %s", ErrSiteConflict, server.name, strings.Join(conflicts, ", "))
+	}
+
+	if err := server.writePasswords(ctx); err != nil {
 		return false, err
 	}
 
-	if err := os.Symlink(server.path(), server.link()); err != nil {
+	if err := writeFile(ctx, server.path(), []byte(managedMarker+"\n"+content), 0644); err != nil {
 		return false, err
 	}
 
-	if err := eOf(exec.Command("sudo", "systemctl", "restart", "nginx").Run()); err != nil {
+	if err := enableSite(ctx, server.name); err != nil {
+		return false, err
+	}
+
+	if err := reloadNginx(ctx, "restart"); err != nil {
 		return false, err
 	} else {
 		return true, nil
 	}
 }
 
+func (server *serverBlock) Ensure() (Result, error) {
+	return server.EnsureContext(context.Background())
+}
+
+func (server *serverBlock) EnsureContext(ctx context.Context) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Unchanged, err
+	}
+
+	content, err := server.compile()
+	if err != nil {
+		return Unchanged, err
+	}
+
+	// missing site created like changed one, nginx reloaded not restarted
+	result := Updated
+	current, err := os.ReadFile(server.path())
+	if os.IsNotExist(err) {
+		result = Created
+	} else if err != nil {
+		return Unchanged, err
+	}
+
+	enabled, err := server.Enabled()
+	if err != nil {
+		return Unchanged, err
+	}
+
+	changed := result == Created || string(current) != managedMarker+"\n"+content
+	if !changed && enabled && server.passwordsMatch(ctx) {
+		return Unchanged, nil
+	}
+
+	if conflicts, err := server.Conflicts(); err != nil {
+		return Unchanged, err
+	} else if len(conflicts) > 0 {
+		return Unchanged, fmt.Errorf("%w: %s: %s", ErrSiteConflict, server.name, strings.Join(conflicts, ", "))
+	}
+
+	if !server.passwordsMatch(ctx) {
+		if err := server.writePasswords(ctx); err != nil {
+			return Unchanged, err
+		}
+	}
+
+	if changed {
+		if err := writeFile(ctx, server.path(), []byte(managedMarker+"\n"+content), 0644); err != nil {
+			return Unchanged, err
+		}
+	}
+
+	if err := enableSite(ctx, server.name); err != nil {
+		return Unchanged, err
+	}
+
+	return result, reloadNginx(ctx, "reload")
+}
+
+// writePasswords writes basic auth passwords file, removes it if no user defined.
+func (server *serverBlock) writePasswords(ctx context.Context) error {
+	if len(server.users) == 0 {
+		if err := remove(ctx, server.passwords()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var passwords strings.Builder
+	for user, password := range server.users {
+		if hash, err := apr1(password); err != nil {
+			return err
+		} else {
+			passwords.WriteString(user + ":" + hash + "\n")
+		}
+	}
+	return WriteFileAtomicContext(ctx, server.passwords(), []byte(passwords.String()), FileOptions{
+		Mode:  0640,
+		Group: nginxGroup(),
+	})
+}
+
+// passwordsMatch checks if basic auth passwords file matches users.
+func (server *serverBlock) passwordsMatch(ctx context.Context) bool {
+	content, err := readFile(ctx, server.passwords())
+	if os.IsNotExist(err) {
+		return len(server.users) == 0
+	} else if err != nil {
+		return false
+	}
+
+	hashes := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if user, hash, ok := strings.Cut(line, ":"); ok {
+			hashes[user] = hash
+		}
+	}
+
+	if len(hashes) != len(server.users) {
+		return false
+	}
+	for user, password := range server.users {
+		// $apr1$<salt>$<hash>
+		parts := strings.Split(hashes[user], "$")
+		if len(parts) != 4 || apr1Salt(password, parts[2]) != hashes[user] {
+			return false
+		}
+	}
+	return true
+}
+
 func (server *serverBlock) Uninstall() error {
-	// Remove the enabled site link
-	if err := os.Remove(server.link()); err != nil && !os.IsNotExist(err) {
+	return server.UninstallContext(context.Background())
+}
+
+func (server *serverBlock) UninstallContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	// Remove the available site file
-	if err := os.Remove(server.path()); err != nil && !os.IsNotExist(err) {
+	if err := removeSite(ctx, server.name); err != nil {
 		return err
 	}
 
 	// Reload nginx to apply the changes
-	return eOf(exec.Command("sudo", "systemctl", "restart", "nginx").Run())
+	return reloadNginx(ctx, "restart")
+}
+
+// Site represents an nginx site installed on the system.
+type Site struct {
+	Name    string
+	Enabled bool
+	Managed bool
+	Domains []string
+	Ports   []string
+}
+
+// ListSites scans sites-available and sites-enabled directories and returns installed sites.
+func ListSites() ([]Site, error) {
+	entries, err := os.ReadDir(nginxAvailableDir)
+	if err != nil {
+		return nil, err
+	}
+
+	sites := make([]Site, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		raw, err := os.ReadFile(nginxAvailableDir + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		enabled, err := FileExists(nginxEnabledDir + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		site := Site{
+			Name:    entry.Name(),
+			Enabled: enabled,
+			Managed: strings.Contains(string(raw), managedMarker),
+			Domains: nginxDirective(string(raw), "server_name"),
+			Ports:   make([]string, 0),
+		}
+		for _, upstream := range nginxDirective(string(raw), "proxy_pass") {
+			if u, err := url.Parse(upstream); err == nil && u.Port() != "" {
+				site.Ports = append(site.Ports, u.Port())
+			}
+		}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// EnableSites enables multiple sites and reloads nginx once.
+func EnableSites(names ...string) error {
+	return EnableSitesContext(context.Background(), names...)
+}
+
+// EnableSitesContext enables multiple sites and reloads nginx once using ctx.
+func EnableSitesContext(ctx context.Context, names ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := enableSite(ctx, name); err != nil {
+			return err
+		}
+	}
+	return reloadNginx(ctx, "reload")
+}
+
+// DisableSites disables multiple sites and reloads nginx once.
+func DisableSites(names ...string) error {
+	return DisableSitesContext(context.Background(), names...)
+}
+
+// DisableSitesContext disables multiple sites and reloads nginx once using ctx.
+func DisableSitesContext(ctx context.Context, names ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := disableSite(ctx, name); err != nil {
+			return err
+		}
+	}
+	return reloadNginx(ctx, "reload")
+}
+
+// RemoveSites removes multiple sites and reloads nginx once.
+func RemoveSites(names ...string) error {
+	return RemoveSitesContext(context.Background(), names...)
+}
+
+// RemoveSitesContext removes multiple sites and reloads nginx once using ctx.
+func RemoveSitesContext(ctx context.Context, names ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := removeSite(ctx, name); err != nil {
+			return err
+		}
+	}
+	return reloadNginx(ctx, "reload")
+}
+
+// enableSite links available site into enabled sites.
+func enableSite(ctx context.Context, name string) error {
+	if exists, err := FileExists(nginxAvailableDir + name); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("%w: %s file not exists", ErrSiteNotFound, nginxAvailableDir+name)
+	}
+
+	if exists, err := FileExists(nginxEnabledDir + name); err != nil || exists {
+		return err
+	}
+	return symlink(ctx, nginxAvailableDir+name, nginxEnabledDir+name)
+}
+
+// disableSite removes site link from enabled sites.
+func disableSite(ctx context.Context, name string) error {
+	if err := remove(ctx, nginxEnabledDir+name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeSite removes site link, file and basic auth passwords file.
+func removeSite(ctx context.Context, name string) error {
+	if err := disableSite(ctx, name); err != nil {
+		return err
+	}
+
+	if err := remove(ctx, nginxAvailableDir+name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Remove the basic auth passwords file
+	if err := remove(ctx, nginxPasswordDir+name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
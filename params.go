@@ -0,0 +1,134 @@
+package unix
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParamType represents type of template parameter used to validate and escape value.
+type ParamType int
+
+const (
+	// TextParam is raw text, not validated nor escaped.
+	TextParam ParamType = iota
+	// TokenParam is a single config token without whitespace and special characters.
+	TokenParam
+	// DomainParam is a space separated list of domain names (e.g. example.com *.example.com).
+	DomainParam
+	// PortParam is a tcp port number.
+	PortParam
+	// PathParam is an absolute file system path.
+	PathParam
+	// ExecParam is a systemd exec line, specifiers and variables escaped.
+	ExecParam
+	// EnvParam is an environment value, quoted for systemd.
+	EnvParam
+)
+
+var domainRx = regexp.MustCompile(`^(\*\.)?([A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)*[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// ValidateToken checks value is a single config token without whitespace and special characters.
+func ValidateToken(value string) error {
+	if strings.ContainsAny(value, " \t\r\n;{}\"'`\\#$") || hasControl(value) {
+		return fmt.Errorf("%q contains invalid characters", value)
+	}
+	return nil
+}
+
+// ValidateDomain checks value is a space separated list of domain names.
+func ValidateDomain(value string) error {
+	for _, domain := range strings.Split(value, " ") {
+		if domain != "_" && (len(domain) > 253 || !domainRx.MatchString(domain)) {
+			return fmt.Errorf("%q is not a valid domain", domain)
+		}
+	}
+	return nil
+}
+
+// ValidatePort checks value is a tcp port number.
+func ValidatePort(value string) error {
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%q is not a valid port", value)
+	}
+	return nil
+}
+
+// ValidatePath checks value is an absolute path without whitespace and special characters.
+func ValidatePath(value string) error {
+	if !path.IsAbs(value) {
+		return fmt.Errorf("%q is not an absolute path", value)
+	} else if strings.ContainsAny(value, "%") {
+		return fmt.Errorf("%q contains invalid characters", value)
+	}
+	return ValidateToken(value)
+}
+
+// ValidateExec checks value is a single line command.
+func ValidateExec(value string) error {
+	if strings.ContainsAny(value, "\r\n") || hasControl(value) || strings.HasSuffix(value, "\\") {
+		return fmt.Errorf("%q is not a single line command", value)
+	}
+	return nil
+}
+
+// ValidateEnv checks value is a valid environment value.
+func ValidateEnv(value string) error {
+	if strings.ContainsRune(value, 0) {
+		return fmt.Errorf("%q contains null character", value)
+	}
+	return nil
+}
+
+// escape validates value and escapes it for target config.
+// empty values are not validated.
+func (kind ParamType) escape(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	switch kind {
+	case TokenParam:
+		return value, ValidateToken(value)
+	case DomainParam:
+		return value, ValidateDomain(value)
+	case PortParam:
+		return value, ValidatePort(value)
+	case PathParam:
+		return value, ValidatePath(value)
+	case ExecParam:
+		return strings.NewReplacer("%", "%%", "$", "$$").Replace(value), ValidateExec(value)
+	case EnvParam:
+		return systemdQuote(value), ValidateEnv(value)
+	default:
+		return value, nil
+	}
+}
+
+// typedValue escapes parameter value and tracks invalid parameters.
+// invalid parameters rendered as empty string.
+func typedValue(invalids map[string]error, name, value string, kind []ParamType) string {
+	delete(invalids, name)
+	if len(kind) == 0 {
+		return value
+	}
+
+	escaped, err := kind[0].escape(value)
+	if err != nil {
+		invalids[name] = err
+		return ""
+	}
+	return escaped
+}
+
+// hasControl checks if value contains control characters.
+func hasControl(value string) bool {
+	for _, r := range value {
+		if r < 0x20 && r != '\t' || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package unix
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Privilege represents privilege escalation mode of commands and file operations.
+type Privilege int
+
+const (
+	// Sudo escalates using sudo, password prompted if required.
+	Sudo Privilege = iota
+	// SudoNonInteractive escalates using sudo -n, fails with ErrPasswordRequired if password required.
+	SudoNonInteractive
+	// Doas escalates using doas.
+	Doas
+	// Pkexec escalates using polkit pkexec.
+	Pkexec
+	// Root never escalates, privileged commands fail with ErrNotRoot if process not running as root.
+	Root
+)
+
+// privilege is the escalation mode of package.
+var privilege = Sudo
+
+// SetPrivilege sets the privilege escalation mode of package, default to Sudo.
+// commands never escalated when process running as root.
+func SetPrivilege(mode Privilege) {
+	privilege = mode
+}
+
+// CheckPrivileges checks if privileged commands could be run with privilege escalation mode.
+func CheckPrivileges() error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+
+	switch privilege {
+	case Root:
+		return ErrNotRoot
+	case SudoNonInteractive:
+		if err := run(context.Background(), exec.Command("sudo", "-n", "true")); errors.Is(err, exec.ErrNotFound) {
+			return err
+		} else if err != nil {
+			return ErrPasswordRequired
+		}
+		return nil
+	default:
+		_, err := exec.LookPath(escalator())
+		return err
+	}
+}
+
+// escalator returns the escalation command of privilege mode.
+func escalator() string {
+	switch privilege {
+	case Doas:
+		return "doas"
+	case Pkexec:
+		return "pkexec"
+	default:
+		return "sudo"
+	}
+}
+
+// privileged creates command escalated by privilege mode.
+// command fails with ErrNotRoot without running in Root mode if process not running as root.
+func privileged(name string, args ...string) *exec.Cmd {
+	if os.Geteuid() == 0 {
+		return exec.Command(name, args...)
+	} else if privilege == Root {
+		cmd := exec.Command(name, args...)
+		cmd.Err = ErrNotRoot
+		return cmd
+	} else if privilege == SudoNonInteractive {
+		return exec.Command("sudo", append([]string{"-n", name}, args...)...)
+	}
+	return exec.Command(escalator(), append([]string{name}, args...)...)
+}
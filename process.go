@@ -0,0 +1,29 @@
+package unix
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// CommandTimeout is the default timeout of each command executed by package, zero disables timeout.
+var CommandTimeout = 5 * time.Minute
+
+// commandWaitDelay is the time to wait for terminated command to exit before killing it.
+const commandWaitDelay = 5 * time.Second
+
+// commandContext creates command bound to ctx.
+// command and its process group terminated when ctx done, killed if not exited after wait delay.
+// stop must be called after command exited to kill remaining processes of canceled command group,
+// returns error if remaining processes could not be killed.
+func commandContext(ctx context.Context, cmd *exec.Cmd) (result *exec.Cmd, stop func() error) {
+	result = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
+	result.Dir = cmd.Dir
+	result.Env = cmd.Env
+	result.Stdin = cmd.Stdin
+	if cmd.Err != nil {
+		result.Err = cmd.Err
+	}
+	result.WaitDelay = commandWaitDelay
+	return result, setProcessGroup(result)
+}
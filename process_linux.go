@@ -0,0 +1,60 @@
+//go:build linux
+
+package unix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setProcessGroup runs command in its own process group to terminate its children on cancel.
+// commands of interactive terminal stay in foreground group to prompt for password,
+// only command terminated and escalation commands (e.g. sudo) relay signal to their children.
+// returned stop kills remaining processes of group after canceled command exited,
+// wait delay kill of exec package only kills the group leader.
+// cleanup is best-effort, privileged children not killable by current user killed using privileged kill.
+func setProcessGroup(cmd *exec.Cmd) func() error {
+	if _, err := unix.IoctlGetTermios(int(os.Stdin.Fd()), unix.TCGETS); err == nil {
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(syscall.SIGTERM)
+		}
+		return func() error { return nil }
+	}
+
+	canceled := false
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// escalation commands (e.g. sudo) relay SIGTERM to privileged children
+		canceled = true
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	return func() error {
+		if !canceled {
+			return nil
+		}
+
+		group := strconv.Itoa(-cmd.Process.Pid)
+		err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		if errors.Is(err, syscall.EPERM) && os.Geteuid() != 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), commandWaitDelay)
+			defer cancel()
+			err = run(ctx, privileged("kill", "-s", "KILL", "--", group))
+			if cmdErr := new(CommandError); errors.As(err, &cmdErr) && strings.Contains(cmdErr.Stderr, "No such process") {
+				err = nil
+			}
+		}
+
+		if err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("kill process group %s: %w", group, err)
+		}
+		return nil
+	}
+}
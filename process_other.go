@@ -0,0 +1,10 @@
+//go:build !linux
+
+package unix
+
+import "os/exec"
+
+// setProcessGroup keeps default cancellation, process groups only supported on linux.
+func setProcessGroup(cmd *exec.Cmd) func() error {
+	return func() error { return nil }
+}
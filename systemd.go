@@ -1,34 +1,43 @@
 package unix
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func NewSystemdService(name, root, command string) SystemdService {
-	service := new(systemdDriver)
-	service.name = name
-	service.root = root
-	service.command = command
-	service.template = NewEngine()
-	service.template.SetTemplate(`
+var envKeyRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+const systemdUnitDir = "/etc/systemd/system/"
+
+// systemdServiceTemplate is the default template of system scope services.
+const systemdServiceTemplate = `
 [Unit]
 Description={name}
 ConditionPathExists={root}
 After=network.target
+{dependencies}
 
 [Service]
 Type=simple
 User=root
 Group=root
-LimitNOFILE=1024
+{resources}
+{environment}
+{hardening}
 
 Restart=on-failure
 RestartSec=10
 
 WorkingDirectory={root}
-ExecStart=/usr/bin/sudo {root}/{command}
+ExecStart={root}/{command}
 
 PermissionsStartOnly=true
 StandardOutput=syslog
@@ -36,42 +45,274 @@ StandardError=syslog
 SyslogIdentifier={name}
 
 [Install]
-WantedBy=multi-user.target
-	`)
+WantedBy={wanted_by}
+	`
+
+// systemdUserServiceTemplate is the default template of user scope services.
+const systemdUserServiceTemplate = `
+[Unit]
+Description={name}
+ConditionPathExists={root}
+After=network.target
+{dependencies}
+
+[Service]
+Type=simple
+{resources}
+{environment}
+{hardening}
+
+Restart=on-failure
+RestartSec=10
+
+WorkingDirectory={root}
+ExecStart={root}/{command}
+
+StandardOutput=syslog
+StandardError=syslog
+SyslogIdentifier={name}
+
+[Install]
+WantedBy={wanted_by}
+	`
+
+func NewSystemdService(name, root, command string) SystemdService {
+	service := new(systemdDriver)
+	service.name = name
+	service.root = root
+	service.command = command
+	service.envs = make(map[string]string)
+	service.resources = DefaultResources()
+	service.dependencies = make(map[Dependency][]string)
+	service.keep = 5
 	return service
 }
 
 type SystemdService interface {
 	// Name sets the name of the service.
 	Name(name string) SystemdService
+	// Unit returns the unit file name of the service.
+	Unit() string
 	// Root sets the root path of the service.
 	Root(dir string) SystemdService
 	// Command sets the command of the service.
 	Command(command string) SystemdService
+	// Env sets environment variable of the service in managed environment file (/etc/<name>/env).
+	Env(key, value string) SystemdService
+	// EnvFile adds external environment file to the service.
+	EnvFile(path string) SystemdService
+	// Harden sets sandboxing options of the service (e.g. BaselineHardening(), StrictHardening()).
+	Harden(hardening Hardening) SystemdService
+	// Limit sets resource limits and cgroup controls of the service.
+	Limit(resources Resources) SystemdService
+	// Depends adds dependency of the service to units (e.g. Depends(After, "postgresql.service")).
+	Depends(dependency Dependency, units ...string) SystemdService
+	// WantedBy sets the targets that want the service on enable. default to multi-user.target.
+	WantedBy(targets ...string) SystemdService
+	// Socket activates the service by socket.
+	// socket installed with service, service is not enabled nor started on install.
+	Socket(socket SystemdSocket) SystemdService
+	// Instanced installs the service as template unit (name@.service) to run multiple instances.
+	// instances numbered from base (e.g. name@8001), instance id passed to command as $INSTANCE environment.
+	// template unit is not enabled nor started on install, use Scale to run instances.
+	Instanced(base int) SystemdService
+	// UserScope manages the service by user systemd instance (systemctl --user) without sudo.
+	// unit files written to ~/.config/systemd/user/ and managed environment to ~/.config/<name>/env.
+	UserScope() SystemdService
+	// KeepReleases sets the number of releases kept by Upgrade including current release. default to 5.
+	KeepReleases(n int) SystemdService
+	// ReloadOnUpgrade reloads (ExecReload) running service on Upgrade instead of restarting it.
+	// service must re-execute its binary on reload (e.g. graceful binary swap on SIGHUP).
+	ReloadOnUpgrade() SystemdService
+	// Backend sets the backend of unit operations (start, stop, enable, reload and properties).
+	// default to systemctl backend of service scope.
+	Backend(backend SystemdBackend) SystemdService
 	// Template sets the template for the service.
-	// template string can contain {name}, {root} and {command} placeholders.
+	// template string can contain {name}, {root}, {command}, {dependencies}, {resources}, {environment}, {hardening} and {wanted_by} placeholders.
 	Template(engine TemplateEngine) SystemdService
 	// Exists checks if the service exists.
 	Exists() bool
+	// ExistsContext checks if the service exists using ctx.
+	ExistsContext(ctx context.Context) (bool, error)
 	// Enabled checks if the service exists and enabled on startup.
 	Enabled() bool
+	// EnabledContext checks if the service exists and enabled on startup using ctx.
+	EnabledContext(ctx context.Context) (bool, error)
 	// Install installs the service.
 	// override parameter indicating whether to override existing configurations.
 	// returns false if service exists and not override.
 	Install(override bool) (bool, error)
+	// InstallContext installs the service using ctx.
+	InstallContext(ctx context.Context, override bool) (bool, error)
+	// Ensure installs the service or updates it if rendered unit or environment file changed.
+	// service restarted only if changed.
+	Ensure() (Result, error)
+	// EnsureContext ensures the service using ctx.
+	EnsureContext(ctx context.Context) (Result, error)
 	// Uninstall uninstalls the service.
 	Uninstall() error
+	// UninstallContext uninstalls the service using ctx.
+	UninstallContext(ctx context.Context) error
+	// EnableLinger keeps user scope services of current user running without login session.
+	EnableLinger() error
+	// EnableLingerContext enables lingering of current user using ctx.
+	EnableLingerContext(ctx context.Context) error
+	// WriteEnv writes managed environment file and restarts the service if content changed.
+	// returns false if content not changed.
+	WriteEnv() (bool, error)
+	// WriteEnvContext writes managed environment file using ctx.
+	WriteEnvContext(ctx context.Context) (bool, error)
+	// Upgrade copies binary to versioned release directory ({root}/releases/<version>) and atomically
+	// switches {root}/current link to it, {root}/<command binary> links to current release binary.
+	// running service restarted (or reloaded) and switched back to previous release if not become active,
+	// failed release removed after rollback. stopped service is not started.
+	Upgrade(binary string) error
+	// UpgradeContext upgrades the service binary using ctx, canceled upgrade rolled back like failed one.
+	UpgradeContext(ctx context.Context, binary string) error
+	// Analyze returns the overall exposure level of service using systemd-analyze security.
+	Analyze() (float64, error)
+	// AnalyzeContext returns the overall exposure level of service using ctx.
+	AnalyzeContext(ctx context.Context) (float64, error)
+	// UpdateResources changes cgroup controls (memory, cpu, tasks and io) of running service
+	// using systemctl set-property without reinstall.
+	UpdateResources(resources Resources) error
+	// UpdateResourcesContext changes cgroup controls of running service using ctx.
+	UpdateResourcesContext(ctx context.Context, resources Resources) error
+	// AddOverride writes named drop-in (/etc/systemd/system/<unit>.d/<name>.conf) and reloads systemd.
+	// content is unit config with sections (e.g. "[Service]\nMemoryMax=1G").
+	// drop-ins applied on next restart, and work for vendor units under /lib/systemd/system too.
+	AddOverride(name, content string) error
+	// AddOverrideContext writes named drop-in and reloads systemd using ctx.
+	AddOverrideContext(ctx context.Context, name, content string) error
+	// Overrides returns names of service drop-ins.
+	Overrides() ([]string, error)
+	// RemoveOverride removes named drop-in and reloads systemd.
+	RemoveOverride(name string) error
+	// RemoveOverrideContext removes named drop-in and reloads systemd using ctx.
+	RemoveOverrideContext(ctx context.Context, name string) error
+	// Scale enables and starts n instances of template unit, extra instances stopped and disabled.
+	Scale(n int) error
+	// ScaleContext scales template unit to n instances using ctx.
+	ScaleContext(ctx context.Context, n int) error
+	// Instances returns ids of running instances of template unit.
+	Instances() ([]string, error)
+	// InstancesContext returns ids of running instances of template unit using ctx.
+	InstancesContext(ctx context.Context) ([]string, error)
+	// RollingRestart restarts running instances one at a time,
+	// waits up to timeout for each instance to become active before restarting next one.
+	RollingRestart(timeout time.Duration) error
+	// RollingRestartContext restarts running instances one at a time using ctx.
+	RollingRestartContext(ctx context.Context, timeout time.Duration) error
 }
 
 type systemdDriver struct {
-	name     string
-	root     string
-	command  string
-	template TemplateEngine
+	systemdScope
+	name         string
+	root         string
+	command      string
+	envs         map[string]string
+	envFiles     []string
+	hardening    Hardening
+	resources    Resources
+	dependencies map[Dependency][]string
+	wantedBy     []string
+	socket       SystemdSocket
+	instanced    bool
+	base         int
+	backend      SystemdBackend
+	keep         int
+	reload       bool
+	template     TemplateEngine
+}
+
+// systemd returns service backend or systemctl backend of scope.
+func (driver systemdDriver) systemd() SystemdBackend {
+	if driver.backend != nil {
+		return driver.backend
+	}
+	return systemctlBackend{driver.systemdScope}
+}
+
+// unit returns unit name of service or instance of template unit.
+func (driver systemdDriver) unit(instance string) string {
+	if driver.instanced {
+		return driver.name + "@" + instance + ".service"
+	}
+	return driver.name + ".service"
+}
+
+// units returns unit pattern matching service or all instances of template unit.
+func (driver systemdDriver) units() string {
+	return driver.unit("*")
 }
 
 func (driver systemdDriver) path() string {
-	return "/etc/systemd/system/" + driver.name + ".service"
+	return driver.unitDir() + driver.unit("")
+}
+
+func (driver systemdDriver) envPath() string {
+	return driver.configDir() + driver.name + "/env"
+}
+
+// environment renders environment file directives.
+func (driver systemdDriver) environment() (string, error) {
+	lines := make([]string, 0)
+	if driver.instanced {
+		lines = append(lines, "Environment=INSTANCE=%i")
+	}
+	if len(driver.envs) > 0 {
+		lines = append(lines, "EnvironmentFile="+driver.envPath())
+	}
+	for _, path := range driver.envFiles {
+		if err := ValidatePath(path); err != nil {
+			return "", err
+		}
+		lines = append(lines, "EnvironmentFile="+path)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// envContent renders managed environment file content.
+func (driver systemdDriver) envContent() (string, error) {
+	keys := make([]string, 0, len(driver.envs))
+	for key := range driver.envs {
+		if !envKeyRx.MatchString(key) {
+			return "", fmt.Errorf("%q is not a valid environment key", key)
+		} else if err := ValidateEnv(driver.envs[key]); err != nil {
+			return "", err
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var content strings.Builder
+	for _, key := range keys {
+		content.WriteString(key + "=" + envQuote(driver.envs[key]) + "\n")
+	}
+	return content.String(), nil
+}
+
+// writeEnv writes managed environment file. returns false if content not changed.
+func (driver systemdDriver) writeEnv(ctx context.Context) (bool, error) {
+	if len(driver.envs) == 0 {
+		return false, nil
+	}
+
+	content, err := driver.envContent()
+	if err != nil {
+		return false, err
+	}
+
+	if current, err := readFile(ctx, driver.envPath()); err == nil && string(current) == content {
+		return false, nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	if err := writeFile(ctx, driver.envPath(), []byte(content), 0600); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 func (driver *systemdDriver) Name(name string) SystemdService {
@@ -79,6 +320,10 @@ func (driver *systemdDriver) Name(name string) SystemdService {
 	return driver
 }
 
+func (driver *systemdDriver) Unit() string {
+	return driver.unit("")
+}
+
 func (driver *systemdDriver) Root(dir string) SystemdService {
 	driver.root = dir
 	return driver
@@ -89,61 +334,537 @@ func (driver *systemdDriver) Command(command string) SystemdService {
 	return driver
 }
 
+func (driver *systemdDriver) Env(key, value string) SystemdService {
+	driver.envs[key] = value
+	return driver
+}
+
+func (driver *systemdDriver) EnvFile(path string) SystemdService {
+	driver.envFiles = append(driver.envFiles, path)
+	return driver
+}
+
+func (driver *systemdDriver) Harden(hardening Hardening) SystemdService {
+	driver.hardening = hardening
+	return driver
+}
+
+func (driver *systemdDriver) Limit(resources Resources) SystemdService {
+	driver.resources = resources
+	return driver
+}
+
+func (driver *systemdDriver) Depends(dependency Dependency, units ...string) SystemdService {
+	for _, unit := range units {
+		if !slices.Contains(driver.dependencies[dependency], unit) {
+			driver.dependencies[dependency] = append(driver.dependencies[dependency], unit)
+		}
+	}
+	return driver
+}
+
+func (driver *systemdDriver) WantedBy(targets ...string) SystemdService {
+	driver.wantedBy = targets
+	return driver
+}
+
+func (driver *systemdDriver) Socket(socket SystemdSocket) SystemdService {
+	driver.socket = socket
+	return driver
+}
+
+func (driver *systemdDriver) Instanced(base int) SystemdService {
+	driver.instanced = true
+	driver.base = base
+	return driver
+}
+
+func (driver *systemdDriver) UserScope() SystemdService {
+	driver.user = true
+	return driver
+}
+
+func (driver *systemdDriver) Backend(backend SystemdBackend) SystemdService {
+	driver.backend = backend
+	return driver
+}
+
 func (driver *systemdDriver) Template(engine TemplateEngine) SystemdService {
 	driver.template = engine
 	return driver
 }
 
 func (driver *systemdDriver) Exists() bool {
-	_, err := exec.Command("sudo", "systemctl", "status", driver.name).Output()
-	return err == nil
+	exists, _ := driver.ExistsContext(context.Background())
+	return exists
+}
+
+func (driver *systemdDriver) ExistsContext(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	} else if driver.instanced {
+		return FileExists(driver.path())
+	}
+
+	state, err := driver.systemd().Property(ctx, driver.unit(""), "LoadState")
+	return err == nil && state != "" && state != "not-found", err
 }
 
 func (driver *systemdDriver) Enabled() bool {
-	output, _ := exec.Command("sudo", "systemctl", "is-enabled", driver.name).Output()
-	return strings.HasPrefix(string(output), "enabled")
+	enabled, _ := driver.EnabledContext(context.Background())
+	return enabled
+}
+
+func (driver *systemdDriver) EnabledContext(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	state, err := driver.systemd().Property(ctx, driver.unit(strconv.Itoa(driver.base)), "UnitFileState")
+	return strings.HasPrefix(state, "enabled"), err
+}
+
+// unitDependencies renders dependency directives of unit section.
+func (driver systemdDriver) unitDependencies() (string, error) {
+	lines := make([]string, 0)
+	if driver.socket != nil {
+		lines = append(lines, "Requires="+driver.socket.Unit(), "After="+driver.socket.Unit())
+	}
+	for _, dependency := range dependencies {
+		for _, unit := range driver.dependencies[dependency] {
+			if err := ValidateToken(unit); err != nil {
+				return "", err
+			}
+		}
+
+		if len(driver.dependencies[dependency]) > 0 {
+			lines = append(lines, string(dependency)+"="+strings.Join(driver.dependencies[dependency], " "))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// engine returns service template engine or default template of scope.
+func (driver *systemdDriver) engine() TemplateEngine {
+	if driver.template != nil {
+		return driver.template
+	} else if driver.user {
+		return NewEngine().SetTemplate(systemdUserServiceTemplate)
+	}
+	return NewEngine().SetTemplate(systemdServiceTemplate)
+}
+
+func (driver *systemdDriver) compile() (string, error) {
+	engine := driver.engine()
+	environment, err := driver.environment()
+	if err != nil {
+		return "", err
+	}
+
+	dependencies, err := driver.unitDependencies()
+	if err != nil {
+		return "", err
+	}
+
+	hardening, err := driver.hardening.directives()
+	if err != nil {
+		return "", err
+	}
+
+	for _, target := range driver.wantedBy {
+		if err := ValidateToken(target); err != nil {
+			return "", err
+		}
+	}
+
+	// default resources and targets could be omitted by custom templates
+	if driver.resources == DefaultResources() {
+		engine.AddOptional("resources", driver.resources.directives())
+	} else {
+		engine.AddParameter("resources", driver.resources.directives())
+	}
+	if len(driver.wantedBy) > 0 {
+		engine.AddParameter("wanted_by", strings.Join(driver.wantedBy, " "))
+	} else if driver.user {
+		engine.AddOptional("wanted_by", "default.target")
+	} else {
+		engine.AddOptional("wanted_by", "multi-user.target")
+	}
+
+	content, err := engine.
+		AddParameter("name", driver.name, TokenParam).
+		AddParameter("root", driver.root, PathParam).
+		AddParameter("command", driver.command, ExecParam).
+		AddParameter("environment", environment).
+		AddParameter("dependencies", dependencies).
+		AddParameter("hardening", hardening).
+		CompileStrict()
+	if err != nil {
+		return "", err
+	}
+	return managedMarker + "\n" + content, nil
 }
 
 func (driver *systemdDriver) Install(override bool) (bool, error) {
-	if exists := driver.Exists(); exists && !override {
+	return driver.InstallContext(context.Background(), override)
+}
+
+func (driver *systemdDriver) InstallContext(ctx context.Context, override bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if exists, err := driver.ExistsContext(ctx); err != nil {
+		return false, err
+	} else if exists && !override {
 		return false, nil
 	}
 
-	content := driver.template.
-		AddParameter("name", driver.name).
-		AddParameter("root", driver.root).
-		AddParameter("command", driver.command).
-		Compile()
+	content, err := driver.compile()
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := driver.writeEnv(ctx); err != nil {
+		return false, err
+	}
 
-	if err := os.WriteFile(driver.path(), []byte(content), 0644); err != nil {
+	if err := writeFile(ctx, driver.path(), []byte(content), 0644); err != nil {
 		return false, err
 	}
 
-	if err := eOf(exec.Command("sudo", "systemctl", "daemon-reload").Run()); err != nil {
+	if err := driver.systemd().Reload(ctx); err != nil {
 		return false, err
 	}
 
-	if err := eOf(exec.Command("sudo", "systemctl", "enable", driver.name).Run()); err != nil {
+	// socket activated service started by socket
+	if driver.socket != nil {
+		if driver.user {
+			driver.socket.UserScope()
+		}
+		return driver.socket.InstallContext(ctx, true)
+	}
+
+	// template unit instances started by scale
+	if driver.instanced {
+		return true, nil
+	}
+
+	if err := driver.systemd().Enable(ctx, driver.unit("")); err != nil {
 		return false, err
 	}
 
-	if err := eOf(exec.Command("sudo", "systemctl", "start", driver.name).Run()); err != nil {
+	if err := driver.systemd().Start(ctx, driver.unit("")); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
+func (driver *systemdDriver) Ensure() (Result, error) {
+	return driver.EnsureContext(context.Background())
+}
+
+func (driver *systemdDriver) EnsureContext(ctx context.Context) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Unchanged, err
+	}
+
+	content, err := driver.compile()
+	if err != nil {
+		return Unchanged, err
+	}
+
+	current, err := os.ReadFile(driver.path())
+	if os.IsNotExist(err) {
+		if _, err := driver.InstallContext(ctx, true); err != nil {
+			return Unchanged, err
+		}
+		return Created, nil
+	} else if err != nil {
+		return Unchanged, err
+	}
+
+	envChanged, err := driver.writeEnv(ctx)
+	if err != nil {
+		return Unchanged, err
+	}
+
+	unitChanged := string(current) != content
+	if !unitChanged && !envChanged {
+		return Unchanged, nil
+	}
+
+	if unitChanged {
+		if err := writeFile(ctx, driver.path(), []byte(content), 0644); err != nil {
+			return Unchanged, err
+		}
+
+		if err := driver.systemd().Reload(ctx); err != nil {
+			return Unchanged, err
+		}
+	}
+
+	// socket activated and template units restarted only if running
+	if driver.socket != nil || driver.instanced {
+		units, err := driver.runningUnits(ctx)
+		if err != nil {
+			return Unchanged, err
+		}
+
+		for _, unit := range units {
+			if err := driver.systemd().TryRestart(ctx, unit); err != nil {
+				return Unchanged, err
+			}
+		}
+		return Updated, nil
+	}
+
+	if err := driver.systemd().Enable(ctx, driver.unit("")); err != nil {
+		return Unchanged, err
+	}
+	return Updated, driver.systemd().Restart(ctx, driver.unit(""))
+}
+
 func (driver *systemdDriver) Uninstall() error {
-	if driver.Exists() {
-		if err := eOf(exec.Command("systemctl", "stop", driver.name).Run()); err != nil {
+	return driver.UninstallContext(context.Background())
+}
+
+func (driver *systemdDriver) UninstallContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if driver.socket != nil {
+		if driver.user {
+			driver.socket.UserScope()
+		}
+		if err := driver.socket.UninstallContext(ctx); err != nil && !os.IsNotExist(err) {
 			return err
 		}
+	}
+
+	exists, err := driver.ExistsContext(ctx)
+	if err != nil {
+		return err
+	} else if driver.instanced {
+		if err := driver.ScaleContext(ctx, 0); err != nil {
+			return err
+		}
+	} else if exists {
+		if err := driver.systemd().Stop(ctx, driver.unit("")); err != nil {
+			return err
+		}
+
+		if err := driver.systemd().Disable(ctx, driver.unit("")); err != nil {
+			return err
+		}
+	}
+
+	if err := remove(ctx, driver.envPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return remove(ctx, driver.path())
+}
+
+func (driver *systemdDriver) EnableLinger() error {
+	return driver.EnableLingerContext(context.Background())
+}
+
+func (driver *systemdDriver) EnableLingerContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return enableLinger(ctx)
+}
+
+func (driver *systemdDriver) WriteEnv() (bool, error) {
+	return driver.WriteEnvContext(context.Background())
+}
+
+func (driver *systemdDriver) WriteEnvContext(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if changed, err := driver.writeEnv(ctx); err != nil || !changed {
+		return false, err
+	}
+
+	units, err := driver.runningUnits(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, unit := range units {
+		if err := driver.systemd().TryRestart(ctx, unit); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
 
-		if err := eOf(exec.Command("systemctl", "disable", driver.name).Run()); err != nil {
+func (driver *systemdDriver) UpdateResources(resources Resources) error {
+	return driver.UpdateResourcesContext(context.Background(), resources)
+}
+
+func (driver *systemdDriver) UpdateResourcesContext(ctx context.Context, resources Resources) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	properties := resources.properties()
+	if len(properties) == 0 {
+		return fmt.Errorf("no cgroup property to update")
+	}
+
+	units, err := driver.runningUnits(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range units {
+		if err := driver.systemd().SetUnitProperties(ctx, unit, properties...); err != nil {
 			return err
 		}
 	}
 
-	return os.Remove(driver.path())
+	driver.resources = resources
+	return nil
+}
+
+func (driver *systemdDriver) Analyze() (float64, error) {
+	return driver.AnalyzeContext(context.Background())
+}
+
+func (driver *systemdDriver) AnalyzeContext(ctx context.Context) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	out, err := output(ctx, driver.analyze("security", "--no-pager", driver.unit(strconv.Itoa(driver.base))))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if _, level, ok := strings.Cut(line, "Overall exposure level for "); ok {
+			if _, score, ok := strings.Cut(level, ": "); ok && len(strings.Fields(score)) > 0 {
+				return strconv.ParseFloat(strings.Fields(score)[0], 64)
+			}
+		}
+	}
+	return 0, fmt.Errorf("%s exposure level not found", driver.name)
+}
+
+// ServiceFilter filters services returned by ListServices.
+type ServiceFilter struct {
+	// Pattern is a shell glob matched against unit names (e.g. "api-*"). empty matches all.
+	Pattern string
+	// Dir is the unit files directory. default to /etc/systemd/system.
+	Dir string
+	// Managed returns only services created by this package.
+	Managed bool
+	// User lists user scope services (systemctl --user) of current user.
+	User bool
+}
+
+// Service represents a systemd service unit installed on the system.
+type Service struct {
+	Name    string
+	Path    string
+	Managed bool
+	Load    string
+	Active  string
+	Sub     string
+	State   string
+}
+
+// ListServices lists service units in unit directory with their status.
+func ListServices(filter ServiceFilter) ([]Service, error) {
+	return ListServicesContext(context.Background(), filter)
+}
+
+// ListServicesContext lists service units in unit directory with their status using ctx.
+func ListServicesContext(ctx context.Context, filter ServiceFilter) ([]Service, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	scope := systemdScope{user: filter.User}
+	dir := filter.Dir
+	if dir == "" {
+		dir = scope.unitDir()
+	}
+	pattern := filter.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	units, err := scope.listUnits(ctx, "*.service")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []struct {
+		UnitFile string `json:"unit_file"`
+		State    string `json:"state"`
+	}
+	if out, err := output(ctx, scope.systemctl("list-unit-files", "--type=service", "--output=json")); err != nil {
+		return nil, err
+	} else if err := json.Unmarshal(out, &files); err != nil {
+		return nil, err
+	}
+
+	services := make([]Service, 0)
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".service")
+		if !ok || entry.IsDir() {
+			continue
+		} else if matched, err := filepath.Match(pattern, name); err != nil {
+			return nil, err
+		} else if !matched {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		service := Service{
+			Name:    name,
+			Path:    path,
+			Managed: strings.Contains(string(raw), managedMarker),
+			Load:    "not-loaded",
+			Active:  "inactive",
+			Sub:     "dead",
+		}
+		if filter.Managed && !service.Managed {
+			continue
+		}
+
+		for _, unit := range units {
+			if unit.Unit == entry.Name() {
+				service.Load = unit.Load
+				service.Active = unit.Active
+				service.Sub = unit.Sub
+			}
+		}
+		for _, file := range files {
+			if file.UnitFile == entry.Name() {
+				service.State = file.State
+			}
+		}
+		services = append(services, service)
+	}
+	return services, nil
 }
@@ -0,0 +1,144 @@
+package unix
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SystemdBackend performs systemd unit operations, operations canceled when ctx done.
+type SystemdBackend interface {
+	// Start starts the unit and waits for job to complete.
+	Start(ctx context.Context, unit string) error
+	// Stop stops the unit and waits for job to complete.
+	Stop(ctx context.Context, unit string) error
+	// Restart restarts the unit and waits for job to complete.
+	Restart(ctx context.Context, unit string) error
+	// ReloadUnit reloads the unit configuration (ExecReload) and waits for job to complete.
+	ReloadUnit(ctx context.Context, unit string) error
+	// TryRestart restarts the unit if running.
+	TryRestart(ctx context.Context, unit string) error
+	// Enable enables unit files on startup.
+	Enable(ctx context.Context, units ...string) error
+	// Disable disables unit files on startup.
+	Disable(ctx context.Context, units ...string) error
+	// Reload reloads systemd manager configuration (daemon-reload).
+	Reload(ctx context.Context) error
+	// Property returns unit property value (e.g. LoadState, ActiveState, UnitFileState).
+	Property(ctx context.Context, unit, name string) (string, error)
+	// ListUnitsByPatterns lists loaded units matching patterns (e.g. app@*.service), including inactive units.
+	ListUnitsByPatterns(ctx context.Context, patterns ...string) ([]UnitStatus, error)
+	// SetUnitProperties sets cgroup properties (e.g. MemoryMax=1073741824) of running unit persistently.
+	SetUnitProperties(ctx context.Context, unit string, properties ...string) error
+}
+
+// UnitStatus represents state of a loaded unit.
+type UnitStatus struct {
+	Unit   string `json:"unit"`
+	Load   string `json:"load"`
+	Active string `json:"active"`
+	Sub    string `json:"sub"`
+}
+
+// NewSystemctlBackend creates a systemd backend spawning systemctl commands.
+// user parameter indicating whether to use user systemd instance (systemctl --user).
+func NewSystemctlBackend(user bool) SystemdBackend {
+	return systemctlBackend{systemdScope{user: user}}
+}
+
+type systemctlBackend struct {
+	scope systemdScope
+}
+
+func (backend systemctlBackend) Start(ctx context.Context, unit string) error {
+	return run(ctx, backend.scope.systemctl("start", unit))
+}
+
+func (backend systemctlBackend) Stop(ctx context.Context, unit string) error {
+	return run(ctx, backend.scope.systemctl("stop", unit))
+}
+
+func (backend systemctlBackend) Restart(ctx context.Context, unit string) error {
+	return run(ctx, backend.scope.systemctl("restart", unit))
+}
+
+func (backend systemctlBackend) ReloadUnit(ctx context.Context, unit string) error {
+	return run(ctx, backend.scope.systemctl("reload", unit))
+}
+
+func (backend systemctlBackend) TryRestart(ctx context.Context, unit string) error {
+	return run(ctx, backend.scope.systemctl("try-restart", unit))
+}
+
+func (backend systemctlBackend) Enable(ctx context.Context, units ...string) error {
+	return run(ctx, backend.scope.systemctl(append([]string{"enable"}, units...)...))
+}
+
+func (backend systemctlBackend) Disable(ctx context.Context, units ...string) error {
+	return run(ctx, backend.scope.systemctl(append([]string{"disable"}, units...)...))
+}
+
+func (backend systemctlBackend) Reload(ctx context.Context) error {
+	return run(ctx, backend.scope.systemctl("daemon-reload"))
+}
+
+func (backend systemctlBackend) Property(ctx context.Context, unit, name string) (string, error) {
+	out, err := output(ctx, backend.scope.systemctl("show", "--property="+name, "--value", unit))
+	return strings.TrimSpace(string(out)), err
+}
+
+func (backend systemctlBackend) ListUnitsByPatterns(ctx context.Context, patterns ...string) ([]UnitStatus, error) {
+	return backend.scope.listUnits(ctx, patterns...)
+}
+
+func (backend systemctlBackend) SetUnitProperties(ctx context.Context, unit string, properties ...string) error {
+	return run(ctx, backend.scope.systemctl(append([]string{"set-property", unit}, properties...)...))
+}
+
+var (
+	// activePoll is the interval between unit state checks.
+	activePoll = 500 * time.Millisecond
+	// activeSettle is the time unit must stay active without restart to be considered started.
+	activeSettle = 3 * time.Second
+)
+
+// waitActive waits up to timeout for unit to become active,
+// then requires unit to stay active without restart for settle period.
+func waitActive(ctx context.Context, backend SystemdBackend, unit string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var since time.Time
+	var started string
+	for {
+		state, _ := backend.Property(ctx, unit, "ActiveState")
+		switch {
+		case state == "failed":
+			return fmt.Errorf("%w: %s failed to start", ErrUnitFailed, unit)
+		case state == "active" && since.IsZero():
+			since, started = time.Now(), unitStart(ctx, backend, unit)
+		case state != "active" && !since.IsZero():
+			return fmt.Errorf("%w: %s not stayed active (%s)", ErrUnitFailed, unit, state)
+		case state == "active":
+			if unitStart(ctx, backend, unit) != started {
+				return fmt.Errorf("%w: %s restarted after start", ErrUnitFailed, unit)
+			} else if time.Since(since) >= activeSettle {
+				return nil
+			}
+		case time.Now().After(deadline):
+			return fmt.Errorf("%w: %s not active after %s", ErrUnitFailed, unit, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(activePoll):
+		}
+	}
+}
+
+// unitStart returns restart count and main process start time of unit, changed if unit restarted.
+func unitStart(ctx context.Context, backend SystemdBackend, unit string) string {
+	restarts, _ := backend.Property(ctx, unit, "NRestarts")
+	timestamp, _ := backend.Property(ctx, unit, "ExecMainStartTimestamp")
+	return restarts + "/" + timestamp
+}
@@ -0,0 +1,331 @@
+package unix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	systemdDest      = "org.freedesktop.systemd1"
+	systemdPath      = dbus.ObjectPath("/org/freedesktop/systemd1")
+	systemdManager   = "org.freedesktop.systemd1.Manager"
+	systemdUnit      = "org.freedesktop.systemd1.Unit"
+	dbusProperties   = "org.freedesktop.DBus.Properties"
+	systemdJobResult = "done"
+)
+
+// NewDBusBackend creates a systemd backend talking to org.freedesktop.systemd1 over D-Bus.
+// address is the bus address (e.g. private dbus-daemon for tests), empty address connects to system bus.
+// use session bus address (DBUS_SESSION_BUS_ADDRESS) for user scope services.
+func NewDBusBackend(address string) (DBusBackend, error) {
+	var conn *dbus.Conn
+	var err error
+	if address == "" {
+		conn, err = dbus.ConnectSystemBus()
+	} else {
+		conn, err = dbus.Connect(address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &dbusBackend{conn: conn}
+	if err := backend.manager().Call(systemdManager+".Subscribe", 0).Err; err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface(systemdManager),
+		dbus.WithMatchMember("JobRemoved"),
+	); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return backend, nil
+}
+
+// UnitState represents active state change of unit.
+type UnitState struct {
+	Unit        string
+	ActiveState string
+	SubState    string
+}
+
+// DBusBackend is a systemd backend over D-Bus with unit state subscription.
+type DBusBackend interface {
+	SystemdBackend
+	// Subscribe streams active state changes of units until cancel called.
+	Subscribe(units ...string) (<-chan UnitState, func(), error)
+	// Close closes the bus connection.
+	Close() error
+}
+
+type dbusBackend struct {
+	conn *dbus.Conn
+}
+
+func (backend *dbusBackend) manager() dbus.BusObject {
+	return backend.conn.Object(systemdDest, systemdPath)
+}
+
+// unitPath loads unit and returns its object path.
+func (backend *dbusBackend) unitPath(ctx context.Context, unit string) (dbus.ObjectPath, error) {
+	var path dbus.ObjectPath
+	err := backend.manager().CallWithContext(ctx, systemdManager+".LoadUnit", 0, unit).Store(&path)
+	return path, dbusError(err)
+}
+
+// job calls manager job method and waits for job to complete.
+func (backend *dbusBackend) job(ctx context.Context, method, unit string) error {
+	signals := make(chan *dbus.Signal, 64)
+	backend.conn.Signal(signals)
+	defer backend.conn.RemoveSignal(signals)
+
+	var job dbus.ObjectPath
+	if err := backend.manager().CallWithContext(ctx, systemdManager+"."+method, 0, unit, "replace").Store(&job); err != nil {
+		return dbusError(err)
+	}
+
+	for {
+		var signal *dbus.Signal
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case signal = <-signals:
+		}
+
+		if signal == nil {
+			return fmt.Errorf("%s job signal channel closed", unit)
+		} else if signal.Name != systemdManager+".JobRemoved" || len(signal.Body) < 4 {
+			continue
+		} else if path, ok := signal.Body[1].(dbus.ObjectPath); !ok || path != job {
+			continue
+		} else if result, _ := signal.Body[3].(string); result != systemdJobResult {
+			return fmt.Errorf("%s %s job %s", unit, strings.ToLower(strings.TrimSuffix(method, "Unit")), result)
+		}
+		return nil
+	}
+}
+
+func (backend *dbusBackend) Start(ctx context.Context, unit string) error {
+	return backend.job(ctx, "StartUnit", unit)
+}
+
+func (backend *dbusBackend) Stop(ctx context.Context, unit string) error {
+	return backend.job(ctx, "StopUnit", unit)
+}
+
+func (backend *dbusBackend) Restart(ctx context.Context, unit string) error {
+	return backend.job(ctx, "RestartUnit", unit)
+}
+
+func (backend *dbusBackend) ReloadUnit(ctx context.Context, unit string) error {
+	return backend.job(ctx, "ReloadUnit", unit)
+}
+
+func (backend *dbusBackend) TryRestart(ctx context.Context, unit string) error {
+	return backend.job(ctx, "TryRestartUnit", unit)
+}
+
+func (backend *dbusBackend) Enable(ctx context.Context, units ...string) error {
+	if err := backend.manager().CallWithContext(ctx, systemdManager+".EnableUnitFiles", 0, units, false, true).Err; err != nil {
+		return dbusError(err)
+	}
+	return backend.Reload(ctx)
+}
+
+func (backend *dbusBackend) Disable(ctx context.Context, units ...string) error {
+	if err := backend.manager().CallWithContext(ctx, systemdManager+".DisableUnitFiles", 0, units, false).Err; err != nil {
+		return dbusError(err)
+	}
+	return backend.Reload(ctx)
+}
+
+func (backend *dbusBackend) Reload(ctx context.Context) error {
+	return dbusError(backend.manager().CallWithContext(ctx, systemdManager+".Reload", 0).Err)
+}
+
+func (backend *dbusBackend) Property(ctx context.Context, unit, name string) (string, error) {
+	path, err := backend.unitPath(ctx, unit)
+	if err != nil {
+		return "", err
+	}
+
+	// type specific properties (e.g. NRestarts) served by unit type interface
+	var value dbus.Variant
+	object := backend.conn.Object(systemdDest, path)
+	err = object.CallWithContext(ctx, dbusProperties+".Get", 0, systemdUnit, name).Store(&value)
+	if typed := unitInterface(unit); err != nil && typed != "" {
+		err = object.CallWithContext(ctx, dbusProperties+".Get", 0, typed, name).Store(&value)
+	}
+	if err != nil {
+		return "", dbusError(err)
+	} else if v, ok := value.Value().(string); ok {
+		return v, nil
+	}
+	return fmt.Sprint(value.Value()), nil
+}
+
+func (backend *dbusBackend) ListUnitsByPatterns(ctx context.Context, patterns ...string) ([]UnitStatus, error) {
+	var units []struct {
+		Name, Description, Load, Active, Sub, Following string
+		Path                                            dbus.ObjectPath
+		JobID                                           uint32
+		JobType                                         string
+		JobPath                                         dbus.ObjectPath
+	}
+	if err := backend.manager().
+		CallWithContext(ctx, systemdManager+".ListUnitsByPatterns", 0, []string{}, patterns).
+		Store(&units); err != nil {
+		return nil, dbusError(err)
+	}
+
+	result := make([]UnitStatus, 0, len(units))
+	for _, unit := range units {
+		result = append(result, UnitStatus{Unit: unit.Name, Load: unit.Load, Active: unit.Active, Sub: unit.Sub})
+	}
+	return result, nil
+}
+
+func (backend *dbusBackend) SetUnitProperties(ctx context.Context, unit string, properties ...string) error {
+	values := make([]dbusProperty, 0, len(properties))
+	for _, property := range properties {
+		if value, err := parseDBusProperty(property); err != nil {
+			return err
+		} else {
+			values = append(values, value)
+		}
+	}
+	return dbusError(backend.manager().CallWithContext(ctx, systemdManager+".SetUnitProperties", 0, unit, false, values).Err)
+}
+
+func (backend *dbusBackend) Subscribe(units ...string) (<-chan UnitState, func(), error) {
+	paths := make(map[dbus.ObjectPath]string)
+	for _, unit := range units {
+		if path, err := backend.unitPath(context.Background(), unit); err != nil {
+			return nil, nil, err
+		} else {
+			paths[path] = unit
+		}
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchInterface(dbusProperties),
+		dbus.WithMatchMember("PropertiesChanged"),
+		dbus.WithMatchArg(0, systemdUnit),
+	}
+	if err := backend.conn.AddMatchSignal(match...); err != nil {
+		return nil, nil, err
+	}
+
+	signals := make(chan *dbus.Signal, 64)
+	states := make(chan UnitState, 64)
+	done := make(chan struct{})
+	backend.conn.Signal(signals)
+	go func() {
+		defer close(states)
+		for {
+			select {
+			case <-done:
+				return
+			case signal, ok := <-signals:
+				if !ok {
+					return
+				}
+
+				unit, ok := paths[signal.Path]
+				if !ok || len(signal.Body) < 2 {
+					continue
+				}
+
+				changed, _ := signal.Body[1].(map[string]dbus.Variant)
+				active, hasActive := changed["ActiveState"]
+				if !hasActive {
+					continue
+				}
+
+				state := UnitState{Unit: unit}
+				state.ActiveState, _ = active.Value().(string)
+				if sub, ok := changed["SubState"]; ok {
+					state.SubState, _ = sub.Value().(string)
+				}
+
+				select {
+				case states <- state:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			backend.conn.RemoveSignal(signals)
+			backend.conn.RemoveMatchSignal(match...)
+			close(done)
+		})
+	}
+	return states, cancel, nil
+}
+
+func (backend *dbusBackend) Close() error {
+	return backend.conn.Close()
+}
+
+// unitInterface returns D-Bus interface of unit type (e.g. org.freedesktop.systemd1.Service).
+func unitInterface(unit string) string {
+	dot := strings.LastIndex(unit, ".")
+	if dot < 0 || dot == len(unit)-1 {
+		return ""
+	}
+	kind := unit[dot+1:]
+	return "org.freedesktop.systemd1." + strings.ToUpper(kind[:1]) + kind[1:]
+}
+
+// dbusError maps systemd D-Bus errors to package errors.
+func dbusError(err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+
+	switch dbusErr.Name {
+	case "org.freedesktop.systemd1.NoSuchUnit":
+		return fmt.Errorf("%w: %w", ErrUnitNotFound, err)
+	case "org.freedesktop.DBus.Error.AccessDenied",
+		"org.freedesktop.DBus.Error.InteractiveAuthorizationRequired":
+		return fmt.Errorf("%w: %w", ErrNotRoot, err)
+	}
+	return err
+}
+
+// dbusProperty represents unit property argument of SetUnitProperties.
+type dbusProperty struct {
+	Name  string
+	Value dbus.Variant
+}
+
+// parseDBusProperty converts numeric cgroup property assignment (e.g. MemoryMax=1024, CPUQuota=50%) to D-Bus property.
+func parseDBusProperty(property string) (dbusProperty, error) {
+	name, value, _ := strings.Cut(property, "=")
+	if name == "CPUQuota" {
+		if percent, err := strconv.ParseUint(strings.TrimSuffix(value, "%"), 10, 64); err == nil {
+			return dbusProperty{"CPUQuotaPerSecUSec", dbus.MakeVariant(percent * 10000)}, nil
+		}
+	} else if value == "infinity" {
+		return dbusProperty{name, dbus.MakeVariant(uint64(math.MaxUint64))}, nil
+	} else if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return dbusProperty{name, dbus.MakeVariant(n)}, nil
+	}
+	return dbusProperty{}, fmt.Errorf("%q is not a supported property", property)
+}
@@ -0,0 +1,93 @@
+package unix
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// dropinDir returns drop-in directory of service.
+// drop-ins in /etc/systemd/system apply to vendor units under /lib/systemd/system too.
+func (driver systemdDriver) dropinDir() string {
+	return driver.unitDir() + driver.unit("") + ".d/"
+}
+
+// validateOverride checks drop-in name is a plain file name inside drop-in directory.
+func validateOverride(name string) error {
+	if err := ValidateToken(name); err != nil {
+		return err
+	} else if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return fmt.Errorf("%q is not a valid override name", name)
+	}
+	return nil
+}
+
+func (driver *systemdDriver) AddOverride(name, content string) error {
+	return driver.AddOverrideContext(context.Background(), name, content)
+}
+
+func (driver *systemdDriver) AddOverrideContext(ctx context.Context, name, content string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateOverride(name); err != nil {
+		return err
+	}
+
+	if err := writeFile(ctx, driver.dropinDir()+name+".conf", []byte(managedMarker+"\n"+content), 0644); err != nil {
+		return err
+	}
+
+	return driver.systemd().Reload(ctx)
+}
+
+func (driver *systemdDriver) Overrides() ([]string, error) {
+	entries, err := os.ReadDir(driver.dropinDir())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	overrides := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".conf"); ok && !entry.IsDir() {
+			overrides = append(overrides, name)
+		}
+	}
+	slices.Sort(overrides)
+	return overrides, nil
+}
+
+func (driver *systemdDriver) RemoveOverride(name string) error {
+	return driver.RemoveOverrideContext(context.Background(), name)
+}
+
+func (driver *systemdDriver) RemoveOverrideContext(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateOverride(name); err != nil {
+		return err
+	}
+
+	if err := remove(ctx, driver.dropinDir()+name+".conf"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// remove empty drop-in directory
+	if overrides, err := driver.Overrides(); err != nil {
+		return err
+	} else if len(overrides) == 0 {
+		if err := remove(ctx, driver.dropinDir()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return driver.systemd().Reload(ctx)
+}
@@ -0,0 +1,133 @@
+package unix
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (driver *systemdDriver) Scale(n int) error {
+	return driver.ScaleContext(context.Background(), n)
+}
+
+func (driver *systemdDriver) ScaleContext(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !driver.instanced {
+		return fmt.Errorf("%s is not a template unit, call Instanced before Scale", driver.name)
+	} else if n < 0 {
+		return fmt.Errorf("%d is not a valid number of instances", n)
+	}
+
+	desired := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		desired = append(desired, strconv.Itoa(driver.base+i))
+	}
+
+	units, err := driver.systemd().ListUnitsByPatterns(ctx, driver.units())
+	if err != nil {
+		return err
+	}
+
+	// stop extra instances
+	for _, unit := range units {
+		if instance := driver.instance(unit.Unit); !slices.Contains(desired, instance) {
+			if err := driver.systemd().Stop(ctx, unit.Unit); err != nil {
+				return err
+			}
+
+			if err := driver.systemd().Disable(ctx, unit.Unit); err != nil {
+				return err
+			}
+		}
+	}
+
+	// start desired instances
+	for _, instance := range desired {
+		if err := driver.systemd().Enable(ctx, driver.unit(instance)); err != nil {
+			return err
+		}
+
+		if err := driver.systemd().Start(ctx, driver.unit(instance)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (driver *systemdDriver) Instances() ([]string, error) {
+	return driver.InstancesContext(context.Background())
+}
+
+func (driver *systemdDriver) InstancesContext(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	units, err := driver.systemd().ListUnitsByPatterns(ctx, driver.units())
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]string, 0)
+	for _, unit := range units {
+		if unit.Active == "active" {
+			instances = append(instances, driver.instance(unit.Unit))
+		}
+	}
+	return instances, nil
+}
+
+func (driver *systemdDriver) RollingRestart(timeout time.Duration) error {
+	return driver.RollingRestartContext(context.Background(), timeout)
+}
+
+func (driver *systemdDriver) RollingRestartContext(ctx context.Context, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	units, err := driver.runningUnits(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range units {
+		if err := driver.systemd().Restart(ctx, unit); err != nil {
+			return err
+		}
+
+		if err := waitActive(ctx, driver.systemd(), unit, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runningUnits returns service unit or running instance units of template unit.
+func (driver *systemdDriver) runningUnits(ctx context.Context) ([]string, error) {
+	if !driver.instanced {
+		return []string{driver.unit("")}, nil
+	}
+
+	instances, err := driver.InstancesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		units = append(units, driver.unit(instance))
+	}
+	return units, nil
+}
+
+// instance extracts instance id from instance unit name.
+func (driver systemdDriver) instance(unit string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(unit, driver.name+"@"), ".service")
+}
@@ -0,0 +1,183 @@
+package unix
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Hardening represents systemd sandboxing options of a service.
+// zero values are not rendered.
+type Hardening struct {
+	// NoNewPrivileges prevents service and its children gaining new privileges.
+	NoNewPrivileges bool
+	// ProtectSystem mounts system directories read-only (true, full or strict).
+	ProtectSystem string
+	// ProtectHome makes home directories inaccessible (true, read-only or tmpfs).
+	ProtectHome string
+	// PrivateTmp sets up private /tmp and /var/tmp for service.
+	PrivateTmp bool
+	// ReadWritePaths makes paths writable when system protected.
+	ReadWritePaths []string
+	// CapabilityBoundingSet limits capabilities of service. empty non-nil slice drops all capabilities.
+	CapabilityBoundingSet []string
+	// SystemCallFilter allows only listed system calls or groups (e.g. @system-service).
+	SystemCallFilter []string
+	// RestrictAddressFamilies allows only listed socket address families (e.g. AF_INET).
+	RestrictAddressFamilies []string
+	// MemoryDenyWriteExecute denies creating writable and executable memory mappings.
+	MemoryDenyWriteExecute bool
+}
+
+// BaselineHardening returns hardening profile compatible with most services.
+func BaselineHardening() Hardening {
+	return Hardening{
+		NoNewPrivileges: true,
+		ProtectSystem:   "full",
+		ProtectHome:     "read-only",
+		PrivateTmp:      true,
+	}
+}
+
+// StrictHardening returns restrictive hardening profile for network services.
+// readWritePaths are the only writable paths of service.
+func StrictHardening(readWritePaths ...string) Hardening {
+	return Hardening{
+		NoNewPrivileges:         true,
+		ProtectSystem:           "strict",
+		ProtectHome:             "true",
+		PrivateTmp:              true,
+		ReadWritePaths:          readWritePaths,
+		CapabilityBoundingSet:   []string{},
+		SystemCallFilter:        []string{"@system-service"},
+		RestrictAddressFamilies: []string{"AF_UNIX", "AF_INET", "AF_INET6"},
+		MemoryDenyWriteExecute:  true,
+	}
+}
+
+// directives renders hardening directives of service section.
+func (hardening Hardening) directives() (string, error) {
+	for _, path := range hardening.ReadWritePaths {
+		// "-" prefix ignores missing paths
+		if err := ValidatePath(strings.TrimPrefix(path, "-")); err != nil {
+			return "", err
+		}
+	}
+	for _, values := range [][]string{
+		{hardening.ProtectSystem, hardening.ProtectHome},
+		hardening.CapabilityBoundingSet,
+		hardening.SystemCallFilter,
+		hardening.RestrictAddressFamilies,
+	} {
+		for _, value := range values {
+			if err := ValidateToken(value); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	lines := make([]string, 0)
+	if hardening.NoNewPrivileges {
+		lines = append(lines, "NoNewPrivileges=true")
+	}
+	if hardening.ProtectSystem != "" {
+		lines = append(lines, "ProtectSystem="+hardening.ProtectSystem)
+	}
+	if hardening.ProtectHome != "" {
+		lines = append(lines, "ProtectHome="+hardening.ProtectHome)
+	}
+	if hardening.PrivateTmp {
+		lines = append(lines, "PrivateTmp=true")
+	}
+	if len(hardening.ReadWritePaths) > 0 {
+		lines = append(lines, "ReadWritePaths="+strings.Join(hardening.ReadWritePaths, " "))
+	}
+	if hardening.CapabilityBoundingSet != nil {
+		lines = append(lines, "CapabilityBoundingSet="+strings.Join(hardening.CapabilityBoundingSet, " "))
+	}
+	if len(hardening.SystemCallFilter) > 0 {
+		lines = append(lines, "SystemCallFilter="+strings.Join(hardening.SystemCallFilter, " "))
+	}
+	if len(hardening.RestrictAddressFamilies) > 0 {
+		lines = append(lines, "RestrictAddressFamilies="+strings.Join(hardening.RestrictAddressFamilies, " "))
+	}
+	if hardening.MemoryDenyWriteExecute {
+		lines = append(lines, "MemoryDenyWriteExecute=true")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Resources represents resource limits and cgroup controls of a service.
+// zero values are not rendered, negative memory, tasks and core values rendered as infinity.
+type Resources struct {
+	// MemoryMax is the hard memory limit in bytes.
+	MemoryMax int64 `json:"memory_max,omitempty" yaml:"memory_max,omitempty" toml:"memory_max,omitempty"`
+	// MemoryHigh is the memory throttling limit in bytes.
+	MemoryHigh int64 `json:"memory_high,omitempty" yaml:"memory_high,omitempty" toml:"memory_high,omitempty"`
+	// CPUQuota is the cpu time limit in percent of one cpu (e.g. 200 for two cpus).
+	CPUQuota int `json:"cpu_quota,omitempty" yaml:"cpu_quota,omitempty" toml:"cpu_quota,omitempty"`
+	// CPUWeight is the relative cpu weight (1-10000).
+	CPUWeight int `json:"cpu_weight,omitempty" yaml:"cpu_weight,omitempty" toml:"cpu_weight,omitempty"`
+	// TasksMax is the maximum number of tasks.
+	TasksMax int `json:"tasks_max,omitempty" yaml:"tasks_max,omitempty" toml:"tasks_max,omitempty"`
+	// IOWeight is the relative io weight (1-10000).
+	IOWeight int `json:"io_weight,omitempty" yaml:"io_weight,omitempty" toml:"io_weight,omitempty"`
+	// LimitNOFILE is the maximum number of open files.
+	LimitNOFILE int `json:"limit_nofile,omitempty" yaml:"limit_nofile,omitempty" toml:"limit_nofile,omitempty"`
+	// LimitCORE is the maximum core dump size in bytes.
+	LimitCORE int64 `json:"limit_core,omitempty" yaml:"limit_core,omitempty" toml:"limit_core,omitempty"`
+	// OOMScoreAdjust is the out of memory killer score adjustment (-1000 to 1000).
+	OOMScoreAdjust int `json:"oom_score_adjust,omitempty" yaml:"oom_score_adjust,omitempty" toml:"oom_score_adjust,omitempty"`
+}
+
+// DefaultResources returns default resources of service.
+func DefaultResources() Resources {
+	return Resources{LimitNOFILE: 1024}
+}
+
+// properties renders cgroup properties could be changed on running service.
+func (resources Resources) properties() []string {
+	limit := func(v int64) string {
+		if v < 0 {
+			return "infinity"
+		}
+		return strconv.FormatInt(v, 10)
+	}
+
+	properties := make([]string, 0)
+	if resources.MemoryMax != 0 {
+		properties = append(properties, "MemoryMax="+limit(resources.MemoryMax))
+	}
+	if resources.MemoryHigh != 0 {
+		properties = append(properties, "MemoryHigh="+limit(resources.MemoryHigh))
+	}
+	if resources.CPUQuota != 0 {
+		properties = append(properties, "CPUQuota="+strconv.Itoa(resources.CPUQuota)+"%")
+	}
+	if resources.CPUWeight != 0 {
+		properties = append(properties, "CPUWeight="+strconv.Itoa(resources.CPUWeight))
+	}
+	if resources.TasksMax != 0 {
+		properties = append(properties, "TasksMax="+limit(int64(resources.TasksMax)))
+	}
+	if resources.IOWeight != 0 {
+		properties = append(properties, "IOWeight="+strconv.Itoa(resources.IOWeight))
+	}
+	return properties
+}
+
+// directives renders resources directives of service section.
+func (resources Resources) directives() string {
+	lines := resources.properties()
+	if resources.LimitNOFILE != 0 {
+		lines = append(lines, "LimitNOFILE="+strconv.Itoa(resources.LimitNOFILE))
+	}
+	if resources.LimitCORE < 0 {
+		lines = append(lines, "LimitCORE=infinity")
+	} else if resources.LimitCORE > 0 {
+		lines = append(lines, "LimitCORE="+strconv.FormatInt(resources.LimitCORE, 10))
+	}
+	if resources.OOMScoreAdjust != 0 {
+		lines = append(lines, "OOMScoreAdjust="+strconv.Itoa(resources.OOMScoreAdjust))
+	}
+	return strings.Join(lines, "\n")
+}
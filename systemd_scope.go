@@ -0,0 +1,70 @@
+package unix
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"os/user"
+)
+
+// systemdScope represents system or user (systemctl --user) systemd instance.
+type systemdScope struct {
+	user bool
+}
+
+// systemctl creates systemctl command of scope.
+// system scope escalated by privilege mode, user scope runs as current user.
+func (scope systemdScope) systemctl(args ...string) *exec.Cmd {
+	if scope.user {
+		return exec.Command("systemctl", append([]string{"--user"}, args...)...)
+	}
+	return privileged("systemctl", args...)
+}
+
+// analyze creates systemd-analyze command of scope.
+func (scope systemdScope) analyze(args ...string) *exec.Cmd {
+	if scope.user {
+		return exec.Command("systemd-analyze", append([]string{"--user"}, args...)...)
+	}
+	return privileged("systemd-analyze", args...)
+}
+
+// unitDir returns unit files directory of scope.
+func (scope systemdScope) unitDir() string {
+	if scope.user {
+		return scope.configDir() + "systemd/user/"
+	}
+	return systemdUnitDir
+}
+
+// configDir returns configuration directory of scope (/etc/ or ~/.config/).
+func (scope systemdScope) configDir() string {
+	if !scope.user {
+		return "/etc/"
+	} else if dir, err := os.UserConfigDir(); err == nil {
+		return dir + "/"
+	}
+	return ".config/"
+}
+
+// listUnits lists loaded units matching patterns.
+func (scope systemdScope) listUnits(ctx context.Context, patterns ...string) ([]UnitStatus, error) {
+	var units []UnitStatus
+	args := append([]string{"list-units", "--all", "--output=json"}, patterns...)
+	if out, err := output(ctx, scope.systemctl(args...)); err != nil {
+		return nil, err
+	} else if err := json.Unmarshal(out, &units); err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+// enableLinger keeps user services running without active login session.
+func enableLinger(ctx context.Context) error {
+	current, err := user.Current()
+	if err != nil {
+		return err
+	}
+	return run(ctx, exec.Command("loginctl", "enable-linger", current.Username))
+}
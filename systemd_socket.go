@@ -0,0 +1,277 @@
+package unix
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// NewSystemdSocket creates a new systemd socket unit.
+// socket activates the service with same name unless Service called.
+func NewSystemdSocket(name string) SystemdSocket {
+	socket := new(socketDriver)
+	socket.name = name
+	socket.template = NewEngine()
+	socket.template.SetTemplate(`
+[Unit]
+Description={name} socket
+
+[Socket]
+{listen}
+{options}
+
+[Install]
+WantedBy=sockets.target
+	`)
+	return socket
+}
+
+type SystemdSocket interface {
+	// Name sets the name of the socket.
+	Name(name string) SystemdSocket
+	// Unit returns the unit file name of the socket.
+	Unit() string
+	// Service sets the unit activated by the socket.
+	Service(unit string) SystemdSocket
+	// ListenStream adds stream (tcp or unix) listen address (e.g. 8080, /run/app.sock).
+	ListenStream(address string) SystemdSocket
+	// ListenDatagram adds datagram (udp or unix) listen address.
+	ListenDatagram(address string) SystemdSocket
+	// SocketMode sets file mode of unix socket.
+	SocketMode(mode os.FileMode) SystemdSocket
+	// SocketOwner sets user and group of unix socket.
+	SocketOwner(user, group string) SystemdSocket
+	// Accept spawns a service instance for each connection, requires template (name@.service) service.
+	Accept(accept bool) SystemdSocket
+	// FileDescriptorName sets the name of passed file descriptors.
+	FileDescriptorName(name string) SystemdSocket
+	// UserScope manages the socket by user systemd instance (systemctl --user) without sudo.
+	UserScope() SystemdSocket
+	// Template sets the template for the socket.
+	// template string can contain {name}, {listen} and {options} placeholders.
+	Template(engine TemplateEngine) SystemdSocket
+	// Exists checks if the socket exists.
+	Exists() bool
+	// ExistsContext checks if the socket exists using ctx.
+	ExistsContext(ctx context.Context) (bool, error)
+	// Install installs, enables and starts the socket.
+	// override parameter indicating whether to override existing configurations.
+	// returns false if socket exists and not override.
+	Install(override bool) (bool, error)
+	// InstallContext installs, enables and starts the socket using ctx.
+	InstallContext(ctx context.Context, override bool) (bool, error)
+	// Uninstall uninstalls the socket.
+	Uninstall() error
+	// UninstallContext uninstalls the socket using ctx.
+	UninstallContext(ctx context.Context) error
+}
+
+type socketDriver struct {
+	systemdScope
+	name      string
+	service   string
+	streams   []string
+	datagrams []string
+	mode      os.FileMode
+	owner     string
+	group     string
+	accept    bool
+	fdName    string
+	template  TemplateEngine
+}
+
+func (socket socketDriver) path() string {
+	return socket.unitDir() + socket.Unit()
+}
+
+// listen renders listen directives of socket section.
+func (socket socketDriver) listen() (string, error) {
+	lines := make([]string, 0)
+	for _, address := range socket.streams {
+		if err := ValidateToken(address); err != nil {
+			return "", err
+		}
+		lines = append(lines, "ListenStream="+address)
+	}
+	for _, address := range socket.datagrams {
+		if err := ValidateToken(address); err != nil {
+			return "", err
+		}
+		lines = append(lines, "ListenDatagram="+address)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// options renders socket options directives of socket section.
+func (socket socketDriver) options() (string, error) {
+	lines := make([]string, 0)
+	if socket.service != "" {
+		lines = append(lines, "Service="+socket.service)
+	}
+	if socket.mode != 0 {
+		lines = append(lines, fmt.Sprintf("SocketMode=%04o", socket.mode.Perm()))
+	}
+	if socket.owner != "" {
+		lines = append(lines, "SocketUser="+socket.owner)
+	}
+	if socket.group != "" {
+		lines = append(lines, "SocketGroup="+socket.group)
+	}
+	if socket.accept {
+		lines = append(lines, "Accept=yes")
+	}
+	if socket.fdName != "" {
+		lines = append(lines, "FileDescriptorName="+socket.fdName)
+	}
+
+	for _, line := range lines {
+		if err := ValidateToken(line); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// compile renders socket unit content.
+func (socket socketDriver) compile() (string, error) {
+	listen, err := socket.listen()
+	if err != nil {
+		return "", err
+	}
+
+	options, err := socket.options()
+	if err != nil {
+		return "", err
+	}
+
+	return socket.template.
+		AddParameter("name", socket.name, TokenParam).
+		AddParameter("listen", listen).
+		AddParameter("options", options).
+		CompileStrict()
+}
+
+func (socket *socketDriver) Name(name string) SystemdSocket {
+	socket.name = name
+	return socket
+}
+
+func (socket *socketDriver) Unit() string {
+	return socket.name + ".socket"
+}
+
+func (socket *socketDriver) Service(unit string) SystemdSocket {
+	socket.service = unit
+	return socket
+}
+
+func (socket *socketDriver) ListenStream(address string) SystemdSocket {
+	socket.streams = append(socket.streams, address)
+	return socket
+}
+
+func (socket *socketDriver) ListenDatagram(address string) SystemdSocket {
+	socket.datagrams = append(socket.datagrams, address)
+	return socket
+}
+
+func (socket *socketDriver) SocketMode(mode os.FileMode) SystemdSocket {
+	socket.mode = mode
+	return socket
+}
+
+func (socket *socketDriver) SocketOwner(user, group string) SystemdSocket {
+	socket.owner = user
+	socket.group = group
+	return socket
+}
+
+func (socket *socketDriver) Accept(accept bool) SystemdSocket {
+	socket.accept = accept
+	return socket
+}
+
+func (socket *socketDriver) FileDescriptorName(name string) SystemdSocket {
+	socket.fdName = name
+	return socket
+}
+
+func (socket *socketDriver) UserScope() SystemdSocket {
+	socket.user = true
+	return socket
+}
+
+func (socket *socketDriver) Template(engine TemplateEngine) SystemdSocket {
+	socket.template = engine
+	return socket
+}
+
+func (socket *socketDriver) Exists() bool {
+	exists, _ := socket.ExistsContext(context.Background())
+	return exists
+}
+
+func (socket *socketDriver) ExistsContext(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	state, err := NewSystemctlBackend(socket.user).Property(ctx, socket.Unit(), "LoadState")
+	return err == nil && state != "" && state != "not-found", err
+}
+
+func (socket *socketDriver) Install(override bool) (bool, error) {
+	return socket.InstallContext(context.Background(), override)
+}
+
+func (socket *socketDriver) InstallContext(ctx context.Context, override bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if exists, err := socket.ExistsContext(ctx); err != nil {
+		return false, err
+	} else if exists && !override {
+		return false, nil
+	}
+
+	content, err := socket.compile()
+	if err != nil {
+		return false, err
+	}
+
+	if err := writeFile(ctx, socket.path(), []byte(managedMarker+"\n"+content), 0644); err != nil {
+		return false, err
+	}
+
+	if err := run(ctx, socket.systemctl("daemon-reload")); err != nil {
+		return false, err
+	}
+
+	if err := run(ctx, socket.systemctl("enable", "--now", socket.Unit())); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (socket *socketDriver) Uninstall() error {
+	return socket.UninstallContext(context.Background())
+}
+
+func (socket *socketDriver) UninstallContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if exists, err := socket.ExistsContext(ctx); err != nil {
+		return err
+	} else if exists {
+		if err := run(ctx, socket.systemctl("disable", "--now", socket.Unit())); err != nil {
+			return err
+		}
+	}
+
+	return remove(ctx, socket.path())
+}
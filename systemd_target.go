@@ -0,0 +1,227 @@
+package unix
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// Dependency represents systemd unit dependency directive.
+type Dependency string
+
+const (
+	Requires  Dependency = "Requires"
+	Wants     Dependency = "Wants"
+	After     Dependency = "After"
+	Before    Dependency = "Before"
+	BindsTo   Dependency = "BindsTo"
+	PartOf    Dependency = "PartOf"
+	Conflicts Dependency = "Conflicts"
+)
+
+// dependencies is the render order of dependency directives.
+var dependencies = []Dependency{Requires, Wants, BindsTo, PartOf, Conflicts, After, Before}
+
+// NewSystemdTarget creates a new systemd target grouping services to start and stop together.
+func NewSystemdTarget(name string, services ...SystemdService) SystemdTarget {
+	target := new(targetDriver)
+	target.name = name
+	target.services = services
+	target.template = NewEngine()
+	target.template.SetTemplate(`
+[Unit]
+Description={name} services
+Wants={services}
+
+[Install]
+WantedBy={wanted_by}
+	`)
+	return target
+}
+
+type SystemdTarget interface {
+	// Name sets the name of the target.
+	Name(name string) SystemdTarget
+	// Unit returns the unit file name of the target.
+	Unit() string
+	// Add adds services to the target.
+	Add(services ...SystemdService) SystemdTarget
+	// UserScope manages the target and its services by user systemd instance (systemctl --user) without sudo.
+	UserScope() SystemdTarget
+	// Template sets the template for the target.
+	// template string can contain {name} and {services} placeholders.
+	Template(engine TemplateEngine) SystemdTarget
+	// Exists checks if the target exists.
+	Exists() (bool, error)
+	// Install installs the target and its services, services become part of the target.
+	// override parameter indicating whether to override existing configurations.
+	// returns false if target exists and not override.
+	Install(override bool) (bool, error)
+	// InstallContext installs the target and its services using ctx.
+	InstallContext(ctx context.Context, override bool) (bool, error)
+	// Uninstall uninstalls the target and its services.
+	Uninstall() error
+	// UninstallContext uninstalls the target and its services using ctx.
+	UninstallContext(ctx context.Context) error
+	// Start starts all services of the target.
+	Start() error
+	// StartContext starts all services of the target using ctx.
+	StartContext(ctx context.Context) error
+	// Stop stops all services of the target.
+	Stop() error
+	// StopContext stops all services of the target using ctx.
+	StopContext(ctx context.Context) error
+}
+
+type targetDriver struct {
+	systemdScope
+	name     string
+	services []SystemdService
+	template TemplateEngine
+}
+
+func (target targetDriver) path() string {
+	return target.unitDir() + target.Unit()
+}
+
+func (target *targetDriver) Name(name string) SystemdTarget {
+	target.name = name
+	return target
+}
+
+func (target targetDriver) Unit() string {
+	return target.name + ".target"
+}
+
+func (target *targetDriver) Add(services ...SystemdService) SystemdTarget {
+	target.services = append(target.services, services...)
+	return target
+}
+
+func (target *targetDriver) UserScope() SystemdTarget {
+	target.user = true
+	return target
+}
+
+func (target *targetDriver) Template(engine TemplateEngine) SystemdTarget {
+	target.template = engine
+	return target
+}
+
+func (target *targetDriver) Exists() (bool, error) {
+	return FileExists(target.path())
+}
+
+func (target *targetDriver) Install(override bool) (bool, error) {
+	return target.InstallContext(context.Background(), override)
+}
+
+func (target *targetDriver) InstallContext(ctx context.Context, override bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if exists, err := target.Exists(); err != nil {
+		return false, err
+	} else if exists && !override {
+		return false, nil
+	}
+
+	units := make([]string, 0, len(target.services))
+	for _, service := range target.services {
+		units = append(units, service.Unit())
+	}
+
+	wantedBy := "multi-user.target"
+	if target.user {
+		wantedBy = "default.target"
+	}
+
+	content, err := target.template.
+		AddOptional("wanted_by", wantedBy).
+		AddParameter("name", target.name, TokenParam).
+		AddParameter("services", strings.Join(units, " ")).
+		CompileStrict()
+	if err != nil {
+		return false, err
+	}
+
+	if err := writeFile(ctx, target.path(), []byte(managedMarker+"\n"+content), 0644); err != nil {
+		return false, err
+	}
+
+	for _, service := range target.services {
+		if target.user {
+			service.UserScope()
+		}
+		service.Depends(PartOf, target.Unit()).WantedBy(target.Unit())
+		if _, err := service.InstallContext(ctx, true); err != nil {
+			return false, err
+		}
+	}
+
+	if err := run(ctx, target.systemctl("daemon-reload")); err != nil {
+		return false, err
+	}
+
+	if err := run(ctx, target.systemctl("enable", "--now", target.Unit())); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (target *targetDriver) Uninstall() error {
+	return target.UninstallContext(context.Background())
+}
+
+func (target *targetDriver) UninstallContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if exists, err := target.Exists(); err != nil {
+		return err
+	} else if exists {
+		if err := run(ctx, target.systemctl("disable", "--now", target.Unit())); err != nil {
+			return err
+		}
+	}
+
+	for _, service := range target.services {
+		if target.user {
+			service.UserScope()
+		}
+		if err := service.UninstallContext(ctx); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := remove(ctx, target.path()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return run(ctx, target.systemctl("daemon-reload"))
+}
+
+func (target *targetDriver) Start() error {
+	return target.StartContext(context.Background())
+}
+
+func (target *targetDriver) StartContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return run(ctx, target.systemctl("start", target.Unit()))
+}
+
+func (target *targetDriver) Stop() error {
+	return target.StopContext(context.Background())
+}
+
+func (target *targetDriver) StopContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return run(ctx, target.systemctl("stop", target.Unit()))
+}
@@ -0,0 +1,207 @@
+package unix
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// upgradeTimeout is the time to wait for upgraded service to become active.
+	upgradeTimeout = 30 * time.Second
+	// releaseFormat is the sortable time layout of release directories.
+	releaseFormat = "20060102150405.000000000"
+)
+
+// binary returns executable name of service command.
+func (driver systemdDriver) binary() string {
+	if fields := strings.Fields(driver.command); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+func (driver systemdDriver) releasesDir() string {
+	return filepath.Join(driver.root, "releases")
+}
+
+func (driver systemdDriver) currentLink() string {
+	return filepath.Join(driver.root, "current")
+}
+
+func (driver *systemdDriver) KeepReleases(n int) SystemdService {
+	driver.keep = n
+	return driver
+}
+
+func (driver *systemdDriver) ReloadOnUpgrade() SystemdService {
+	driver.reload = true
+	return driver
+}
+
+func (driver *systemdDriver) Upgrade(binary string) error {
+	return driver.UpgradeContext(context.Background(), binary)
+}
+
+func (driver *systemdDriver) UpgradeContext(ctx context.Context, binary string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if driver.binary() == "" {
+		return fmt.Errorf("%s command not set", driver.name)
+	}
+
+	// migrate installed binary to first release
+	if err := driver.migrateRelease(ctx); err != nil {
+		return err
+	}
+
+	previous, err := os.Readlink(driver.currentLink())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// copy new binary into versioned release
+	release := time.Now().UTC().Format(releaseFormat)
+	dir := filepath.Join(driver.releasesDir(), release)
+	if err := mkdirAll(ctx, dir, 0755); err != nil {
+		return err
+	}
+	if err := copyFile(ctx, binary, filepath.Join(dir, driver.binary()), 0755); err != nil {
+		return err
+	}
+
+	units, err := driver.activeUnits(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := driver.switchRelease(ctx, filepath.Join("releases", release)); err != nil {
+		return err
+	}
+
+	if err := driver.restartUnits(ctx, units, driver.reload); err != nil {
+		// rollback to previous release, rollback not canceled with ctx
+		rollback := context.WithoutCancel(ctx)
+		if previous == "" {
+			return err
+		} else if rollbackErr := driver.switchRelease(rollback, previous); rollbackErr != nil {
+			return fmt.Errorf("%w, rollback failed: %w", err, rollbackErr)
+		} else if rollbackErr := driver.restartUnits(rollback, units, false); rollbackErr != nil {
+			return fmt.Errorf("%w, rollback failed: %w", err, rollbackErr)
+		} else if removeErr := removeAll(rollback, dir); removeErr != nil {
+			return fmt.Errorf("%w, rolled back to %s, failed release not removed: %w", err, filepath.Base(previous), removeErr)
+		}
+		return fmt.Errorf("%w, rolled back to %s", err, filepath.Base(previous))
+	}
+
+	return driver.pruneReleases(ctx)
+}
+
+// migrateRelease moves installed binary into a release and links it to current release.
+func (driver systemdDriver) migrateRelease(ctx context.Context) error {
+	path := filepath.Join(driver.root, driver.binary())
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return symlink(ctx, filepath.Join("current", driver.binary()), path)
+	} else if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return err
+	}
+
+	release := filepath.Join("releases", info.ModTime().UTC().Format(releaseFormat))
+	if err := mkdirAll(ctx, filepath.Join(driver.root, release), 0755); err != nil {
+		return err
+	}
+	if err := rename(ctx, path, filepath.Join(driver.root, release, driver.binary())); err != nil {
+		return err
+	}
+	if err := driver.switchRelease(ctx, release); err != nil {
+		return err
+	}
+	return symlink(ctx, filepath.Join("current", driver.binary()), path)
+}
+
+// switchRelease atomically points current link to release.
+func (driver systemdDriver) switchRelease(ctx context.Context, release string) error {
+	tmp := driver.currentLink() + ".tmp"
+	if err := remove(ctx, tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := symlink(ctx, release, tmp); err != nil {
+		return err
+	}
+	return rename(ctx, tmp, driver.currentLink())
+}
+
+// activeUnits returns running units of service, stopped units are not restarted on upgrade.
+func (driver *systemdDriver) activeUnits(ctx context.Context) ([]string, error) {
+	units, err := driver.runningUnits(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]string, 0, len(units))
+	for _, unit := range units {
+		if state, err := driver.systemd().Property(ctx, unit, "ActiveState"); err != nil {
+			return nil, err
+		} else if state == "active" || state == "reloading" || state == "activating" {
+			active = append(active, unit)
+		}
+	}
+	return active, nil
+}
+
+// restartUnits restarts or reloads units and waits for them to become active.
+func (driver *systemdDriver) restartUnits(ctx context.Context, units []string, reload bool) error {
+	for _, unit := range units {
+		var err error
+		if reload {
+			err = driver.systemd().ReloadUnit(ctx, unit)
+		} else {
+			err = driver.systemd().Restart(ctx, unit)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := waitActive(ctx, driver.systemd(), unit, upgradeTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneReleases removes old releases except current one.
+func (driver systemdDriver) pruneReleases(ctx context.Context) error {
+	entries, err := os.ReadDir(driver.releasesDir())
+	if err != nil {
+		return err
+	}
+
+	current, err := os.Readlink(driver.currentLink())
+	if err != nil {
+		return err
+	}
+
+	releases := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != filepath.Base(current) {
+			releases = append(releases, entry.Name())
+		}
+	}
+	slices.Sort(releases)
+
+	// current release counted in kept releases
+	for len(releases) > max(driver.keep-1, 0) {
+		if err := removeAll(ctx, filepath.Join(driver.releasesDir(), releases[0])); err != nil {
+			return err
+		}
+		releases = releases[1:]
+	}
+	return nil
+}
@@ -1,34 +1,528 @@
 package unix
 
-import "strings"
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
 
+// placeholderRx matches {placeholder} tokens of templates.
+var placeholderRx = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// NewEngine creates a template engine with literal {placeholder} replacement.
 func NewEngine() TemplateEngine {
 	engine := new(engineDriver)
 	engine.params = make([]string, 0)
+	engine.optionals = make([]string, 0)
+	engine.invalids = make(map[string]error)
+	return engine
+}
+
+// NewTextEngine creates a template engine backed by text/template.
+// {placeholder} syntax is supported for parameters alongside text/template actions.
+//
+// Supported helper functions:
+// nginx: quotes value for nginx config.
+// systemd: quotes value for systemd unit.
+// default: returns fallback if value is empty (e.g. {{ default "80" .port }}).
+// join: joins list with separator (e.g. {{ join .domains " " }}).
+func NewTextEngine() TemplateEngine {
+	engine := new(textDriver)
+	engine.values = make(map[string]any)
+	engine.optionals = make([]string, 0)
+	engine.invalids = make(map[string]error)
 	return engine
 }
 
+// NewEngineFS creates a literal template engine from name template file of file system (e.g. embed.FS).
+func NewEngineFS(fsys fs.FS, name string) (TemplateEngine, error) {
+	engine := NewEngine().(*engineDriver)
+	engine.source = &templateSource{fsys: fsys, name: name}
+	if err := engine.load(); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// NewEngineFile creates a literal template engine from template file.
+func NewEngineFile(path string) (TemplateEngine, error) {
+	return NewEngineFS(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
+
+// NewTextEngineFS creates a text template engine from name template file of file system (e.g. embed.FS).
+// partials are glob patterns of templates could be included by file name (e.g. {{ template "ssl.conf" . }}).
+func NewTextEngineFS(fsys fs.FS, name string, partials ...string) (TemplateEngine, error) {
+	engine := NewTextEngine().(*textDriver)
+	engine.source = &templateSource{fsys: fsys, name: name, partials: partials}
+	if err := engine.load(); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// NewTextEngineFile creates a text template engine from template file.
+// partials are glob patterns relative to template file directory.
+func NewTextEngineFile(path string, partials ...string) (TemplateEngine, error) {
+	return NewTextEngineFS(os.DirFS(filepath.Dir(path)), filepath.Base(path), partials...)
+}
+
 type TemplateEngine interface {
+	// SetTemplate sets the template string.
 	SetTemplate(template string) TemplateEngine
-	AddParameter(name, value string) TemplateEngine
+	// Watch reloads template files on compile if changed.
+	// has no effect on engines not loaded from file.
+	Watch() TemplateEngine
+	// AddParameter adds string parameter to template.
+	// kind used to validate and escape value, invalid parameters reported on strict compile.
+	AddParameter(name, value string, kind ...ParamType) TemplateEngine
+	// AddOptional adds string parameter which is not reported as unused in strict compile.
+	AddOptional(name, value string, kind ...ParamType) TemplateEngine
+	// AddValue adds any value (e.g. list for loops) to template.
+	AddValue(name string, value any) TemplateEngine
+	// Compile compiles template with parameters.
 	Compile() string
+	// CompileStrict compiles template with parameters.
+	// returns error if template contains placeholders without parameter,
+	// non-empty parameters not used in template or invalid typed parameters.
+	CompileStrict() (string, error)
 }
 
 type engineDriver struct {
-	template string
-	params   []string
+	template  string
+	params    []string
+	optionals []string
+	invalids  map[string]error
+	source    *templateSource
+}
+
+func (e *engineDriver) load() error {
+	if content, _, err := e.source.load(); err != nil {
+		return err
+	} else {
+		e.template = content
+		return nil
+	}
 }
 
 func (e *engineDriver) SetTemplate(template string) TemplateEngine {
 	e.template = template
+	e.source = nil
+	return e
+}
+
+func (e *engineDriver) Watch() TemplateEngine {
+	if e.source != nil {
+		e.source.watch = true
+	}
 	return e
 }
 
-func (e *engineDriver) AddParameter(name, value string) TemplateEngine {
+func (e *engineDriver) AddParameter(name, value string, kind ...ParamType) TemplateEngine {
+	value = typedValue(e.invalids, name, value, kind)
+	for i := 0; i+1 < len(e.params); i += 2 {
+		if e.params[i] == "{"+name+"}" {
+			e.params[i+1] = value
+			return e
+		}
+	}
 	e.params = append(e.params, "{"+name+"}", value)
 	return e
 }
 
+func (e *engineDriver) AddOptional(name, value string, kind ...ParamType) TemplateEngine {
+	e.optionals = append(e.optionals, name)
+	return e.AddParameter(name, value, kind...)
+}
+
+func (e *engineDriver) AddValue(name string, value any) TemplateEngine {
+	return e.AddParameter(name, fmt.Sprint(value))
+}
+
 func (e *engineDriver) Compile() string {
+	if e.source.changed() {
+		e.load()
+	}
 	return strings.NewReplacer(e.params...).Replace(e.template)
 }
+
+func (e *engineDriver) CompileStrict() (string, error) {
+	if e.source.changed() {
+		if err := e.load(); err != nil {
+			return "", err
+		}
+	}
+
+	values := make(map[string]any)
+	for i := 0; i+1 < len(e.params); i += 2 {
+		values[strings.Trim(e.params[i], "{}")] = e.params[i+1]
+	}
+
+	used := placeholders(e.template)
+	if err := strictError(used, values, e.optionals, e.invalids); err != nil {
+		return "", err
+	}
+	return e.Compile(), nil
+}
+
+type textDriver struct {
+	template  string
+	partials  map[string]string
+	values    map[string]any
+	optionals []string
+	invalids  map[string]error
+	source    *templateSource
+}
+
+func (e *textDriver) load() error {
+	if content, partials, err := e.source.load(); err != nil {
+		return err
+	} else {
+		e.template = content
+		e.partials = partials
+		return nil
+	}
+}
+
+func (e *textDriver) SetTemplate(template string) TemplateEngine {
+	e.template = template
+	e.partials = nil
+	e.source = nil
+	return e
+}
+
+func (e *textDriver) Watch() TemplateEngine {
+	if e.source != nil {
+		e.source.watch = true
+	}
+	return e
+}
+
+func (e *textDriver) AddParameter(name, value string, kind ...ParamType) TemplateEngine {
+	e.values[name] = typedValue(e.invalids, name, value, kind)
+	return e
+}
+
+func (e *textDriver) AddOptional(name, value string, kind ...ParamType) TemplateEngine {
+	e.optionals = append(e.optionals, name)
+	return e.AddParameter(name, value, kind...)
+}
+
+func (e *textDriver) AddValue(name string, value any) TemplateEngine {
+	delete(e.invalids, name)
+	e.values[name] = value
+	return e
+}
+
+// Compile compiles template with parameters. returns empty string if template is invalid.
+func (e *textDriver) Compile() string {
+	if e.source.changed() {
+		e.load()
+	}
+
+	if tpl, err := e.parse("zero"); err != nil {
+		return ""
+	} else if result, err := e.execute(tpl, e.zeroValues(tpl)); err != nil {
+		return ""
+	} else {
+		return result
+	}
+}
+
+func (e *textDriver) CompileStrict() (string, error) {
+	if e.source.changed() {
+		if err := e.load(); err != nil {
+			return "", err
+		}
+	}
+
+	tpl, err := e.parse("error")
+	if err != nil {
+		return "", err
+	}
+
+	used := placeholders(e.template)
+	for _, partial := range e.partials {
+		for _, name := range placeholders(partial) {
+			if !slices.Contains(used, name) {
+				used = append(used, name)
+			}
+		}
+	}
+	for _, t := range tpl.Templates() {
+		if t.Tree != nil {
+			templateFields(t.Tree.Root, true, &used)
+		}
+	}
+	if err := strictError(used, e.values, e.optionals, e.invalids); err != nil {
+		return "", err
+	}
+	return e.execute(tpl, e.values)
+}
+
+// parse converts {placeholder} of known parameters to text/template action and parse template.
+func (e *textDriver) parse(missingKey string) (*template.Template, error) {
+	convert := func(content string) string {
+		return replacePlaceholders(content, func(name string) (string, bool) {
+			if _, ok := e.values[name]; ok {
+				return "{{." + name + "}}", true
+			}
+			return "", false
+		})
+	}
+
+	tpl, err := template.New("").
+		Funcs(templateFuncs).
+		Option("missingkey=" + missingKey).
+		Parse(convert(e.template))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, partial := range e.partials {
+		if _, err := tpl.New(name).Parse(convert(partial)); err != nil {
+			return nil, err
+		}
+	}
+	return tpl, nil
+}
+
+func (e *textDriver) execute(tpl *template.Template, values map[string]any) (string, error) {
+	var result strings.Builder
+	if err := tpl.Execute(&result, values); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// zeroValues returns values with empty string for missing root fields of template.
+// text/template renders missing keys of map as <no value>.
+func (e *textDriver) zeroValues(tpl *template.Template) map[string]any {
+	values := maps.Clone(e.values)
+	for _, t := range tpl.Templates() {
+		var fields []string
+		if t.Tree != nil {
+			templateFields(t.Tree.Root, true, &fields)
+		}
+		for _, field := range fields {
+			if value, ok := values[field]; !ok || value == nil {
+				values[field] = ""
+			}
+		}
+	}
+	return values
+}
+
+// templateFuncs helper functions of text template engine.
+var templateFuncs = template.FuncMap{
+	"nginx":   func(v any) string { return nginxQuote(fmt.Sprint(v)) },
+	"systemd": func(v any) string { return systemdQuote(fmt.Sprint(v)) },
+	"default": func(fallback, v any) any {
+		if v == nil || fmt.Sprint(v) == "" {
+			return fallback
+		}
+		return v
+	},
+	"join": func(v []string, sep string) string { return strings.Join(v, sep) },
+}
+
+// replacePlaceholders replaces {placeholder} tokens using resolver. ${variable} tokens are ignored.
+func replacePlaceholders(content string, resolver func(name string) (string, bool)) string {
+	var result strings.Builder
+	last := 0
+	for _, match := range placeholderRx.FindAllStringSubmatchIndex(content, -1) {
+		if match[0] > 0 && content[match[0]-1] == '$' {
+			continue
+		}
+		if value, ok := resolver(content[match[2]:match[3]]); ok {
+			result.WriteString(content[last:match[0]])
+			result.WriteString(value)
+			last = match[1]
+		}
+	}
+	result.WriteString(content[last:])
+	return result.String()
+}
+
+// placeholders extracts {placeholder} names of template. ${variable} tokens are ignored.
+func placeholders(content string) []string {
+	result := make([]string, 0)
+	replacePlaceholders(content, func(name string) (string, bool) {
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+		return "", false
+	})
+	return result
+}
+
+// templateFields collects root field names referenced by text/template nodes.
+// dot reports whether dot is root data, fields inside range and with bodies are not root fields unless accessed through $.
+func templateFields(node parse.Node, dot bool, fields *[]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n != nil {
+			for _, child := range n.Nodes {
+				templateFields(child, dot, fields)
+			}
+		}
+	case *parse.ActionNode:
+		templateFields(n.Pipe, dot, fields)
+	case *parse.IfNode:
+		templateFields(&n.BranchNode, dot, fields)
+	case *parse.RangeNode:
+		templateFields(n.Pipe, dot, fields)
+		templateFields(n.List, false, fields)
+		templateFields(n.ElseList, dot, fields)
+	case *parse.WithNode:
+		templateFields(n.Pipe, dot, fields)
+		templateFields(n.List, false, fields)
+		templateFields(n.ElseList, dot, fields)
+	case *parse.BranchNode:
+		templateFields(n.Pipe, dot, fields)
+		templateFields(n.List, dot, fields)
+		templateFields(n.ElseList, dot, fields)
+	case *parse.TemplateNode:
+		templateFields(n.Pipe, dot, fields)
+	case *parse.PipeNode:
+		if n != nil {
+			for _, cmd := range n.Cmds {
+				templateFields(cmd, dot, fields)
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			templateFields(arg, dot, fields)
+		}
+	case *parse.ChainNode:
+		templateFields(n.Node, dot, fields)
+	case *parse.FieldNode:
+		if dot {
+			*fields = append(*fields, n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			*fields = append(*fields, n.Ident[1])
+		}
+	}
+}
+
+// strictError reports placeholders without parameter, unused non-empty parameters and invalid parameters.
+func strictError(used []string, values map[string]any, optionals []string, invalids map[string]error) error {
+	missing := make([]string, 0)
+	for _, name := range used {
+		if _, ok := values[name]; !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+
+	unused := make([]string, 0)
+	for name, value := range values {
+		if !slices.Contains(used, name) && !slices.Contains(optionals, name) && fmt.Sprint(value) != "" {
+			unused = append(unused, name)
+		}
+	}
+	slices.Sort(unused)
+
+	messages := make([]string, 0)
+	if len(missing) > 0 {
+		messages = append(messages, "missing parameters: "+strings.Join(missing, ", "))
+	}
+	if len(unused) > 0 {
+		messages = append(messages, "unused parameters: "+strings.Join(unused, ", "))
+	}
+	if len(invalids) > 0 {
+		names := make([]string, 0, len(invalids))
+		for name := range invalids {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			messages = append(messages, "invalid "+name+" parameter: "+invalids[name].Error())
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("invalid template, %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// templateSource loads template and partials from file system.
+type templateSource struct {
+	fsys     fs.FS
+	name     string
+	partials []string
+	watch    bool
+	loaded   time.Time
+}
+
+// files returns template file followed by partial files.
+func (s *templateSource) files() ([]string, error) {
+	files := []string{s.name}
+	for _, pattern := range s.partials {
+		matches, err := fs.Glob(s.fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if !slices.Contains(files, match) {
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+// load reads template content and partials keyed by file name.
+func (s *templateSource) load() (string, map[string]string, error) {
+	files, err := s.files()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var content string
+	partials := make(map[string]string)
+	for i, file := range files {
+		raw, err := fs.ReadFile(s.fsys, file)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if i == 0 {
+			content = string(raw)
+		} else {
+			partials[path.Base(file)] = string(raw)
+		}
+	}
+	s.loaded = time.Now()
+	return content, partials, nil
+}
+
+// changed checks if watched template files modified after last load.
+func (s *templateSource) changed() bool {
+	if s == nil || !s.watch {
+		return false
+	}
+
+	files, err := s.files()
+	if err != nil {
+		return false
+	}
+
+	for _, file := range files {
+		if info, err := fs.Stat(s.fsys, file); err == nil && info.ModTime().After(s.loaded) {
+			return true
+		}
+	}
+	return false
+}
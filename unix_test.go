@@ -1,8 +1,21 @@
 package unix_test
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/godbus/dbus/v5"
 	"github.com/mekramy/unix"
 )
 
@@ -39,3 +52,884 @@ func TestPrintF(t *testing.T) {
 		unix.PrintF(tt.format, tt.args...)
 	}
 }
+
+func TestTextEngine(t *testing.T) {
+	result := unix.NewTextEngine().
+		SetTemplate(`server_name {domains};{{ range .backends }}
+server {{ . }};{{ end }}
+listen {{ default "80" .port }};
+root {{ nginx .root }};`).
+		AddParameter("domains", "example.com").
+		AddValue("backends", []string{"127.0.0.1:8001", "127.0.0.1:8002"}).
+		AddParameter("root", "/var/www/my site").
+		Compile()
+
+	expected := `server_name example.com;
+server 127.0.0.1:8001;
+server 127.0.0.1:8002;
+listen 80;
+root "/var/www/my site";`
+	if result != expected {
+		t.Fatal("FAIL", result)
+	}
+
+	result = unix.NewTextEngine().
+		SetTemplate(`listen {{ .port }};{{ if .ssl }} ssl{{ end }} {{ $.name }};`).
+		Compile()
+	if result != "listen ; ;" {
+		t.Fatal("FAIL missing keys", result)
+	}
+}
+
+func TestCompileStrict(t *testing.T) {
+	engines := []unix.TemplateEngine{unix.NewEngine(), unix.NewTextEngine()}
+	for _, engine := range engines {
+		_, err := engine.
+			SetTemplate("ExecStart={root}/{comand} ${HOME}").
+			AddParameter("root", "/opt/app").
+			AddParameter("command", "app").
+			CompileStrict()
+
+		if err == nil || err.Error() != "invalid template, missing parameters: comand; unused parameters: command" {
+			t.Fatal("FAIL", err)
+		}
+	}
+}
+
+func TestCompileStrictRange(t *testing.T) {
+	type backend struct{ Host string }
+	result, err := unix.NewTextEngine().
+		SetTemplate(`{{ range .backends }}server {{ .Host }} {{ $.weight }};{{ end }}{{ with .primary }}{{ .Host }}{{ end }}`).
+		AddValue("backends", []backend{{"127.0.0.1:8001"}, {"127.0.0.1:8002"}}).
+		AddValue("primary", backend{"127.0.0.1:8001"}).
+		AddParameter("weight", "2").
+		CompileStrict()
+
+	if err != nil {
+		t.Fatal("FAIL", err)
+	} else if result != "server 127.0.0.1:8001 2;server 127.0.0.1:8002 2;127.0.0.1:8001" {
+		t.Fatal("FAIL", result)
+	}
+
+	_, err = unix.NewTextEngine().
+		SetTemplate(`{{ range .backends }}server {{ .Host }} {{ $.weight }};{{ end }}`).
+		AddValue("backends", []backend{{"127.0.0.1:8001"}}).
+		CompileStrict()
+	if err == nil || err.Error() != "invalid template, missing parameters: weight" {
+		t.Fatal("FAIL", err)
+	}
+}
+
+func TestTextEngineFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"site.conf":            {Data: []byte(`server { {{ template "listen.conf" . }} server_name {domains}; }`)},
+		"partials/listen.conf": {Data: []byte(`listen {port};`)},
+	}
+
+	engine, err := unix.NewTextEngineFS(fsys, "site.conf", "partials/*.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := engine.
+		AddParameter("domains", "example.com").
+		AddParameter("port", "80").
+		CompileStrict()
+	if err != nil {
+		t.Fatal(err)
+	} else if result != "server { listen 80; server_name example.com; }" {
+		t.Fatal("FAIL", result)
+	}
+}
+
+func TestTypedParameters(t *testing.T) {
+	result, err := unix.NewEngine().
+		SetTemplate("server_name {domains}; ExecStart={command}").
+		AddParameter("domains", "example.com *.example.com", unix.DomainParam).
+		AddParameter("command", "app --rate 50% --home $HOME", unix.ExecParam).
+		CompileStrict()
+	if err != nil {
+		t.Fatal(err)
+	} else if result != "server_name example.com *.example.com; ExecStart=app --rate 50%% --home $$HOME" {
+		t.Fatal("FAIL", result)
+	}
+
+	_, err = unix.NewEngine().
+		SetTemplate("server_name {domains};").
+		AddParameter("domains", "example.com; return 200", unix.DomainParam).
+		CompileStrict()
+	if err == nil {
+		t.Fatal("FAIL injected domain accepted")
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	manifests := map[string]string{
+		"host.yaml": "services:\n  - name: app\n    root: /opt/app\n    command: app\n    resources:\n      memory_max: 1024\njobs:\n  - command: backup\n    schedule: \"*/15 * * * *\"\n",
+		"host.json": `{"services":[{"name":"app","root":"/opt/app","command":"app","resources":{"memory_max":1024}}],"jobs":[{"command":"backup","schedule":"*/15 * * * *"}]}`,
+		"host.toml": "[[services]]\nname = \"app\"\nroot = \"/opt/app\"\ncommand = \"app\"\n[services.resources]\nmemory_max = 1024\n[[jobs]]\ncommand = \"backup\"\nschedule = \"*/15 * * * *\"\n",
+	}
+
+	dir := t.TempDir()
+	for name, content := range manifests {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		manifest, err := unix.LoadManifest(path)
+		if err != nil {
+			t.Fatal(name, err)
+		} else if len(manifest.Services) != 1 || manifest.Services[0].Resources == nil ||
+			manifest.Services[0].Resources.MemoryMax != 1024 || len(manifest.Jobs) != 1 {
+			t.Fatal("FAIL", name, manifest)
+		} else if job, err := unix.NewCronJob(manifest.Jobs[0].Command).Schedule(manifest.Jobs[0].Schedule); err != nil {
+			t.Fatal(name, err)
+		} else if job.Compile() != "*/15 * * * * backup" {
+			t.Fatal("FAIL", name, job.Compile())
+		}
+	}
+
+	invalid := map[string]string{
+		"service.yaml": "services:\n  - name: app\n    root: /opt/app\n",
+		"site.yaml":    "sites:\n  - name: app\n    domains: [example.com]\n",
+		"job.yaml":     "jobs:\n  - command: backup\n    schedule: \"0 3 * *\"\n",
+		"deps.yaml":    "services:\n  - name: app\n    root: /opt/app\n    command: app\n    dependencies:\n      Require: [db.service]\n",
+	}
+	for name, content := range invalid {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := unix.LoadManifest(path); err == nil {
+			t.Fatal("FAIL invalid", name, "accepted")
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"@hourly", "0 * * * * backup"},
+		{"@reboot", "@reboot backup"},
+		{"*/15 * * * *", "*/15 * * * * backup"},
+		{"0-30/10 1,13 1-15 jan-jun mon-fri", "0-30/10 1,13 1-15 jan-jun mon-fri backup"},
+		{"0 3 * *", ""},
+		{"0 3 * * * *", ""},
+		{"60 * * * *", ""},
+		{"* 24 * * *", ""},
+		{"* * 0 * *", ""},
+		{"*/0 * * * *", ""},
+		{"* * * foo *", ""},
+		{"@often", ""},
+	}
+	for _, tt := range tests {
+		job, err := unix.NewCronJob("backup").Schedule(tt.expr)
+		if tt.expected == "" && err == nil {
+			t.Fatal("FAIL", tt.expr, "accepted")
+		} else if tt.expected != "" && (err != nil || job.Compile() != tt.expected) {
+			t.Fatal("FAIL", tt.expr, err, job.Compile())
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf", "site.conf")
+	for _, content := range []string{"first", "second"} {
+		if err := unix.WriteFileAtomic(path, []byte(content), unix.FileOptions{Mode: 0600}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if data, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "second" {
+		t.Fatal("FAIL", string(data))
+	}
+
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Fatal("FAIL", info.Mode())
+	}
+
+	if entries, err := os.ReadDir(filepath.Dir(path)); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 1 {
+		t.Fatal("FAIL temporary files left", len(entries))
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	var err error = &unix.CommandError{
+		Command:  "sudo",
+		Args:     []string{"crontab", "-l"},
+		ExitCode: 1,
+		Stderr:   "no crontab for root\n",
+		Err:      errors.New("exit status 1"),
+	}
+
+	var cmdErr *unix.CommandError
+	if !errors.Is(err, unix.ErrCrontabMissing) || errors.Is(err, unix.ErrUnitNotFound) {
+		t.Fatal("FAIL", err)
+	} else if !errors.As(fmt.Errorf("wrapped: %w", err), &cmdErr) || cmdErr.ExitCode != 1 {
+		t.Fatal("FAIL", err)
+	} else if err.Error() != "sudo crontab -l failed with exit code 1: no crontab for root" {
+		t.Fatal("FAIL", err.Error())
+	}
+
+	if err := (&unix.CommandError{Command: "nginx", ExitCode: -1}); err.Error() != "nginx failed with exit code -1: " {
+		t.Fatal("FAIL", err.Error())
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	dir := t.TempDir()
+	unix.SetNginxDir(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "sites-enabled"), 0755); err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(filepath.Join(dir, "sites-enabled", "blog"), []byte("server_name example.com;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	site := unix.NewNginxReverseProxy("app", "8000").Domains("example.com")
+	if err := site.Enable(); !errors.Is(err, unix.ErrSiteNotFound) {
+		t.Fatal("FAIL", err)
+	} else if _, err := site.Install(false); !errors.Is(err, unix.ErrSiteConflict) {
+		t.Fatal("FAIL", err)
+	} else if err := unix.NewSystemdService("app", "/opt/app", "app").Scale(2); err == nil || errors.Is(err, unix.ErrUnitNotFound) {
+		t.Fatal("FAIL", err)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := unix.NewCronJob("do some").ExistsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatal("FAIL", err)
+	} else if _, err := unix.NewSystemdService("app", "/opt/app", "app").ExistsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatal("FAIL", err)
+	} else if err := unix.WriteFileAtomicContext(ctx, filepath.Join(t.TempDir(), "site.conf"), nil, unix.FileOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatal("FAIL", err)
+	}
+}
+
+func TestNginxDirectives(t *testing.T) {
+	content := `server {
+        listen 80 default_server; # main
+        listen [::]:80;
+        # server_name commented.com;
+        server_name example.com www.example.com;
+}
+server { listen 8080; server_name "api.example.com"; location / { proxy_pass http://127.0.0.1:8000; } }`
+
+	tests := []struct {
+		directive string
+		expected  string
+	}{
+		{"listen", "[[80 default_server] [[::]:80] [8080]]"},
+		{"server_name", "[[example.com www.example.com] [api.example.com]]"},
+		{"proxy_pass", "[[http://127.0.0.1:8000]]"},
+		{"root", "[]"},
+	}
+	for _, tt := range tests {
+		if result := fmt.Sprint(unix.NginxDirectives(content, tt.directive)); result != tt.expected {
+			t.Fatal("FAIL", tt.directive, result)
+		}
+	}
+
+	if result := fmt.Sprint(unix.NginxDefaultListens(content)); result != "[*:80]" {
+		t.Fatal("FAIL", result)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	dir := t.TempDir()
+	unix.SetNginxDir(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "sites-enabled"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sites := map[string]string{
+		"blog":  "listen 80 default_server;\nserver_name blog.example.com example.com;",
+		"other": "listen 8080;\nserver_name other.com _;",
+		"app":   "listen 80 default_server;\nserver_name example.com;",
+		"wild":  "server { listen 0.0.0.0:443 default_server; server_name *.Example.org; }",
+	}
+	for name, content := range sites {
+		if err := os.WriteFile(filepath.Join(dir, "sites-enabled", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		template  string
+		domain    string
+		conflicts int
+	}{
+		{"listen 80 default_server;\nserver_name {domains};", "example.com", 2},
+		{"listen 80;\nserver_name {domains};", "example.com", 1},
+		{"listen 8080 default_server;\nserver_name {domains};", "_", 0},
+		{"listen 0.0.0.0:80 default_server;\nserver_name {domains};", "new.com", 1},
+		{"listen *:443 default_server;\nserver_name {domains};", "api.example.org", 2},
+		{"listen 443;\nserver_name {domains};", "Api.Example.ORG", 1},
+		{"listen 443;\nserver_name {domains};", "example.org", 0},
+	}
+	for _, tt := range tests {
+		conflicts, err := unix.NewNginxReverseProxy("app", "8000").
+			Domains(tt.domain).
+			Template(unix.NewEngine().SetTemplate(tt.template)).
+			Conflicts()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(conflicts) != tt.conflicts {
+			t.Fatal("FAIL", tt.template, conflicts)
+		}
+	}
+}
+
+func TestListSites(t *testing.T) {
+	dir := t.TempDir()
+	unix.SetNginxDir(dir)
+	for _, sub := range []string{"sites-available", "sites-enabled"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sites := map[string]string{
+		"app":     "# managed by github.com/mekramy/unix\nserver_name app.com www.app.com;\nproxy_pass http://localhost:8000;",
+		"default": "server_name _;\nproxy_pass http://unix:/run/app.sock;",
+	}
+	for name, content := range sites {
+		if err := os.WriteFile(filepath.Join(dir, "sites-available", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "sites-available", "app"), filepath.Join(dir, "sites-enabled", "app")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[{app true true [app.com www.app.com] [8000]} {default false false [_] []}]"
+	if result, err := unix.ListSites(); err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(result) != expected {
+		t.Fatal("FAIL", result)
+	}
+}
+
+func TestRemoveSite(t *testing.T) {
+	dir := t.TempDir()
+	unix.SetNginxDir(dir)
+	for _, path := range []string{"sites-available/app", "sites-enabled/app", "htpasswd/app"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(filepath.Join(dir, path), []byte("server_name example.com;"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := unix.RemoveSite(context.Background(), "app"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"sites-available/app", "sites-enabled/app", "htpasswd/app"} {
+		if _, err := os.Lstat(filepath.Join(dir, path)); !os.IsNotExist(err) {
+			t.Fatal("FAIL", path, "not removed", err)
+		}
+	}
+}
+
+func TestEnvContent(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"plain", `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\dir`, `"C:\\dir"`},
+		{"$HOME `id`", "\"\\$HOME \\`id\\`\""},
+	}
+	for _, tt := range tests {
+		if result := unix.EnvQuote(tt.value); result != tt.expected {
+			t.Fatal("FAIL", tt.value, result)
+		}
+	}
+
+	content, err := unix.EnvContent(unix.NewSystemdService("app", "/opt/app", "app").
+		Env("PORT", "8000").
+		Env("DSN", "postgres://user:p@ss$word@localhost/db"))
+	if err != nil {
+		t.Fatal(err)
+	} else if content != "DSN=\"postgres://user:p@ss\\$word@localhost/db\"\nPORT=\"8000\"\n" {
+		t.Fatal("FAIL", content)
+	}
+
+	for _, service := range []unix.SystemdService{
+		unix.NewSystemdService("app", "/opt/app", "app").Env("1PORT", "8000"),
+		unix.NewSystemdService("app", "/opt/app", "app").Env("PORT", "8000\x00"),
+	} {
+		if _, err := unix.EnvContent(service); err == nil {
+			t.Fatal("FAIL invalid environment accepted")
+		}
+	}
+}
+
+func TestResourcesDirectives(t *testing.T) {
+	tests := []struct {
+		resources unix.Resources
+		expected  string
+	}{
+		{unix.Resources{}, ""},
+		{unix.DefaultResources(), "LimitNOFILE=1024"},
+		{unix.Resources{MemoryMax: 1 << 30, MemoryHigh: -1, CPUQuota: 150, TasksMax: -1}, "MemoryMax=1073741824\nMemoryHigh=infinity\nCPUQuota=150%\nTasksMax=infinity"},
+		{unix.Resources{CPUWeight: 200, IOWeight: 50, LimitCORE: -1, OOMScoreAdjust: -500}, "CPUWeight=200\nIOWeight=50\nLimitCORE=infinity\nOOMScoreAdjust=-500"},
+		{unix.Resources{LimitNOFILE: 65536, LimitCORE: 4096}, "LimitNOFILE=65536\nLimitCORE=4096"},
+	}
+	for _, tt := range tests {
+		if result := unix.ResourcesDirectives(tt.resources); result != tt.expected {
+			t.Fatal("FAIL", tt.resources, result)
+		}
+	}
+}
+
+func TestSocketContent(t *testing.T) {
+	tests := []struct {
+		socket   unix.SystemdSocket
+		expected string
+	}{
+		{
+			unix.NewSystemdSocket("app").ListenStream("8080").ListenDatagram("9090"),
+			"ListenStream=8080\nListenDatagram=9090\n",
+		},
+		{
+			unix.NewSystemdSocket("app").
+				ListenStream("/run/app.sock").
+				Service("api.service").
+				SocketMode(0660).
+				SocketOwner("app", "www-data").
+				Accept(true).
+				FileDescriptorName("http"),
+			"ListenStream=/run/app.sock\nService=api.service\nSocketMode=0660\nSocketUser=app\nSocketGroup=www-data\nAccept=yes\nFileDescriptorName=http",
+		},
+	}
+	for _, tt := range tests {
+		content, err := unix.SocketContent(tt.socket)
+		if err != nil {
+			t.Fatal(err)
+		} else if !strings.Contains(content, "[Socket]\n"+tt.expected) {
+			t.Fatal("FAIL", content)
+		}
+	}
+
+	if _, err := unix.SocketContent(unix.NewSystemdSocket("app").ListenStream("8080\nExecStartPre=/bin/sh")); err == nil {
+		t.Fatal("FAIL injected listen accepted")
+	}
+}
+
+func TestCronCompare(t *testing.T) {
+	job := unix.NewCronJob("backup").EveryXMinutes(15)
+	tests := []struct {
+		lines    []string
+		expected unix.Result
+	}{
+		{nil, unix.Created},
+		{[]string{"@daily cleanup", ""}, unix.Created},
+		{[]string{"@daily cleanup", "*/15 * * * * backup"}, unix.Unchanged},
+		{[]string{"*/15  *  * * *   backup "}, unix.Unchanged},
+		{[]string{"*/30 * * * * backup"}, unix.Updated},
+		{[]string{"@reboot backup"}, unix.Updated},
+	}
+	for _, tt := range tests {
+		if result := unix.CronCompare(job, tt.lines...); result != tt.expected {
+			t.Fatal("FAIL", tt.lines, result)
+		}
+	}
+}
+
+func TestApr1(t *testing.T) {
+	// openssl passwd -apr1 -salt abcdefgh password
+	if result := unix.Apr1Salt("password", "abcdefgh"); result != "$apr1$abcdefgh$FBwExRW4dCc8aL.OvjpIE1" {
+		t.Fatal("FAIL", result)
+	}
+}
+
+func TestSiteZones(t *testing.T) {
+	zones := make(map[string]string)
+	for _, name := range []string{"a.b", "a_b"} {
+		content, err := unix.SiteContent(unix.NewNginxReverseProxy(name, "8000").Domains("example.com").RateLimit("10r/s", 5))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zone := unix.NginxDirectives(content, "limit_req_zone")[0][1]
+		if other, ok := zones[zone]; ok {
+			t.Fatal("FAIL", name, "zone collides with", other)
+		}
+		zones[zone] = name
+	}
+}
+
+func TestHTTPSTarget(t *testing.T) {
+	tests := []struct {
+		target   unix.ProxyTarget
+		expected []string
+	}{
+		{unix.TCPTarget("localhost", "8000"), nil},
+		{unix.HTTPSTarget("localhost", "8443"), []string{"on", "off", ""}},
+		{unix.HTTPSTarget("localhost", "8443").Verify("/etc/ssl/ca.pem", "api.local"), []string{"on", "on", "api.local"}},
+	}
+	for _, tt := range tests {
+		content, err := unix.SiteContent(unix.NewNginxReverseProxy("app", "").Domains("example.com").Target(tt.target))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := []string{
+			fmt.Sprint(unix.NginxDirectives(content, "proxy_ssl_server_name")),
+			fmt.Sprint(unix.NginxDirectives(content, "proxy_ssl_verify")),
+			fmt.Sprint(unix.NginxDirectives(content, "proxy_ssl_name")),
+		}
+		expected := []string{"[]", "[]", "[]"}
+		for i, value := range tt.expected {
+			if value != "" {
+				expected[i] = "[[" + value + "]]"
+			}
+		}
+		if fmt.Sprint(result) != fmt.Sprint(expected) {
+			t.Fatal("FAIL", tt.target, result)
+		}
+	}
+
+	for _, target := range []unix.ProxyTarget{
+		unix.HTTPSTarget("localhost", "8443").Verify("ca.pem", ""),
+		unix.HTTPSTarget("localhost", "8443").Verify("/etc/ssl/ca.pem;\ninclude /etc/shadow", ""),
+		unix.HTTPSTarget("localhost", "8443").Verify("/etc/ssl/ca.pem", "api.local; return 200"),
+		unix.HTTPSTarget("localhost", "8443").Verify("", "api.local"),
+	} {
+		if _, err := unix.SiteContent(unix.NewNginxReverseProxy("app", "").Domains("example.com").Target(target)); err == nil {
+			t.Fatal("FAIL injected target accepted", target)
+		}
+	}
+}
+
+func TestSiteValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		site unix.ServerBlock
+	}{
+		{"rate", unix.NewNginxReverseProxy("app", "8000").RateLimit("1r/s;\ninclude /etc/shadow", 0)},
+		{"allow", unix.NewNginxReverseProxy("app", "8000").Allow("10.0.0.0/8; return 200")},
+		{"deny", unix.NewNginxReverseProxy("app", "8000").Deny("all;\n}")},
+		{"user agents", unix.NewNginxReverseProxy("app", "8000").BlockUserAgents(`bot)") { return 200; } if ("`)},
+		{"user agents escape", unix.NewNginxReverseProxy("app", "8000").BlockUserAgents(`bot\`)},
+		{"realm", unix.NewNginxReverseProxy("app", "8000").BasicAuth("admin\n}", map[string]string{"admin": "secret"})},
+		{"user colon", unix.NewNginxReverseProxy("app", "8000").BasicAuth("admin", map[string]string{"admin:x": "secret"})},
+		{"user space", unix.NewNginxReverseProxy("app", "8000").BasicAuth("admin", map[string]string{"ad min": "secret"})},
+		{"user control", unix.NewNginxReverseProxy("app", "8000").BasicAuth("admin", map[string]string{"admin\n": "secret"})},
+	}
+	for _, tt := range tests {
+		if _, err := unix.SiteContent(tt.site.Domains("example.com")); err == nil {
+			t.Fatal("FAIL injected", tt.name, "accepted")
+		}
+	}
+
+	content, err := unix.SiteContent(unix.NewNginxReverseProxy("app", "8000").
+		Domains("example.com").
+		RateLimit("10r/s", 5).
+		Allow("10.0.0.0/8").
+		Deny("all").
+		BlockUserAgents(`curl/\d+`, "bot").
+		BasicAuth(`x\"; include /etc/shadow; #`, map[string]string{"admin": "secret"}))
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(content, `if ($http_user_agent ~* "(curl/\d+|bot)") {`) {
+		t.Fatal("FAIL", content)
+	} else if !strings.Contains(content, `auth_basic "x\\\"; include /etc/shadow; #";`) {
+		t.Fatal("FAIL", content)
+	}
+}
+
+func TestServiceValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		service unix.SystemdService
+	}{
+		{"read write paths", unix.NewSystemdService("app", "/opt/app", "app").Harden(unix.StrictHardening("/var/lib/app\nExecStartPre=/bin/sh"))},
+		{"relative read write paths", unix.NewSystemdService("app", "/opt/app", "app").Harden(unix.StrictHardening("data"))},
+		{"protect system", unix.NewSystemdService("app", "/opt/app", "app").Harden(unix.Hardening{ProtectSystem: "full\nUser=root"})},
+		{"wanted by", unix.NewSystemdService("app", "/opt/app", "app").WantedBy("multi-user.target\n[Service]")},
+	}
+	for _, tt := range tests {
+		if _, err := unix.ServiceContent(tt.service); err == nil {
+			t.Fatal("FAIL injected", tt.name, "accepted")
+		}
+	}
+
+	if _, err := unix.ServiceContent(unix.NewSystemdService("app", "/opt/app", "app").
+		Harden(unix.StrictHardening("/var/lib/app", "-/var/log/app")).
+		WantedBy("multi-user.target", "app.target")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHardeningProfiles(t *testing.T) {
+	tests := []struct {
+		hardening unix.Hardening
+		expected  string
+	}{
+		{unix.BaselineHardening(), "NoNewPrivileges=true\nProtectSystem=full\nProtectHome=read-only\nPrivateTmp=true\n"},
+		{unix.StrictHardening("/var/lib/app"), "NoNewPrivileges=true\nProtectSystem=strict\nProtectHome=true\nPrivateTmp=true\n" +
+			"ReadWritePaths=/var/lib/app\nCapabilityBoundingSet=\nSystemCallFilter=@system-service\n" +
+			"RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6\nMemoryDenyWriteExecute=true\n"},
+	}
+	for _, tt := range tests {
+		content, err := unix.ServiceContent(unix.NewSystemdService("app", "/opt/app", "app --port 8000").Harden(tt.hardening))
+		if err != nil {
+			t.Fatal(err)
+		} else if !strings.Contains(content, tt.expected) {
+			t.Fatal("FAIL", content)
+		} else if !strings.Contains(content, "\nUser=root\n") || !strings.Contains(content, "\nExecStart=/opt/app/app --port 8000\n") {
+			t.Fatal("FAIL", content)
+		}
+	}
+}
+
+func TestScaleNegative(t *testing.T) {
+	if err := unix.NewSystemdService("app", "/opt/app", "app").Instanced(8001).Scale(-1); err == nil {
+		t.Fatal("FAIL negative scale accepted")
+	}
+}
+
+func TestOverrideName(t *testing.T) {
+	service := unix.NewSystemdService("app", "/opt/app", "app")
+	for _, name := range []string{"", ".", "..", "../../../etc/cron.d/evil", "limits/memory", "limits;"} {
+		if err := service.AddOverride(name, "[Service]\nMemoryMax=1G"); err == nil {
+			t.Fatal("FAIL", name, "accepted")
+		} else if err := service.RemoveOverride(name); err == nil {
+			t.Fatal("FAIL", name, "accepted")
+		}
+	}
+}
+
+func TestRootPrivilege(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("running as root")
+	}
+
+	unix.SetPrivilege(unix.Root)
+	defer unix.SetPrivilege(unix.Sudo)
+	if _, err := unix.NewCronJob("do some").Exists(); !errors.Is(err, unix.ErrNotRoot) {
+		t.Fatal("FAIL", err)
+	}
+}
+
+// fakeSystemd replays unit property values, last value repeated.
+type fakeSystemd struct {
+	unix.SystemdBackend
+	states   []string
+	restarts []string
+}
+
+func (fake *fakeSystemd) Property(_ context.Context, _, name string) (string, error) {
+	values := &fake.states
+	if name == "NRestarts" {
+		values = &fake.restarts
+	} else if name != "ActiveState" {
+		return "", nil
+	}
+
+	value := (*values)[0]
+	if len(*values) > 1 {
+		*values = (*values)[1:]
+	}
+	return value, nil
+}
+
+func TestWaitActive(t *testing.T) {
+	unix.SetActivePolling(time.Millisecond, 20*time.Millisecond)
+	defer unix.SetActivePolling(500*time.Millisecond, 3*time.Second)
+
+	tests := []struct {
+		name     string
+		states   []string
+		restarts []string
+		failed   bool
+	}{
+		{"settled", []string{"activating", "active"}, []string{"0"}, false},
+		{"active then failed", []string{"active", "active", "failed"}, []string{"0"}, true},
+		{"active then restarting", []string{"active", "activating", "active"}, []string{"0"}, true},
+		{"restarted", []string{"active"}, []string{"0", "0", "1"}, true},
+		{"never active", []string{"activating"}, []string{"0"}, true},
+	}
+	for _, test := range tests {
+		backend := &fakeSystemd{states: test.states, restarts: test.restarts}
+		err := unix.WaitActive(context.Background(), backend, "app.service", 50*time.Millisecond)
+		if test.failed != errors.Is(err, unix.ErrUnitFailed) || (!test.failed && err != nil) {
+			t.Fatal("FAIL", test.name, err)
+		}
+	}
+}
+
+func TestParseDBusProperty(t *testing.T) {
+	tests := []struct {
+		property string
+		name     string
+		value    any
+	}{
+		{"MemoryMax=1073741824", "MemoryMax", uint64(1073741824)},
+		{"MemoryMax=infinity", "MemoryMax", uint64(math.MaxUint64)},
+		{"CPUQuota=50%", "CPUQuotaPerSecUSec", uint64(500000)},
+		{"TasksMax=64", "TasksMax", uint64(64)},
+		{"MemoryMax=1G", "", nil},
+		{"CPUQuota=half", "", nil},
+		{"MemoryMax", "", nil},
+	}
+	for _, tt := range tests {
+		name, value, err := unix.ParseDBusProperty(tt.property)
+		if tt.name == "" && err == nil {
+			t.Fatal("FAIL", tt.property, "accepted")
+		} else if tt.name != "" && (err != nil || name != tt.name || value != tt.value) {
+			t.Fatal("FAIL", tt.property, name, value, err)
+		}
+	}
+}
+
+func TestDBusError(t *testing.T) {
+	other := errors.New("other")
+	tests := []struct {
+		err      error
+		expected error
+	}{
+		{dbus.Error{Name: "org.freedesktop.systemd1.NoSuchUnit"}, unix.ErrUnitNotFound},
+		{dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}, unix.ErrNotRoot},
+		{dbus.Error{Name: "org.freedesktop.DBus.Error.InteractiveAuthorizationRequired"}, unix.ErrNotRoot},
+		{fmt.Errorf("call: %w", dbus.Error{Name: "org.freedesktop.systemd1.NoSuchUnit"}), unix.ErrUnitNotFound},
+		{other, other},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		if err := unix.DBusError(tt.err); !errors.Is(err, tt.expected) || (tt.expected == nil && err != nil) {
+			t.Fatal("FAIL", tt.err, err)
+		}
+	}
+
+	if err := unix.DBusError(dbus.Error{Name: "org.freedesktop.DBus.Error.Failed"}); errors.Is(err, unix.ErrUnitNotFound) || errors.Is(err, unix.ErrNotRoot) {
+		t.Fatal("FAIL", err)
+	}
+}
+
+// fakeManager serves minimal org.freedesktop.systemd1 manager and unit objects on a private bus.
+type fakeManager struct {
+	conn *dbus.Conn
+	jobs atomic.Uint32
+}
+
+func (manager *fakeManager) Subscribe() *dbus.Error {
+	return nil
+}
+
+func (manager *fakeManager) LoadUnit(name string) (dbus.ObjectPath, *dbus.Error) {
+	if name == "missing.service" {
+		return "", dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []any{"unit " + name + " not found"})
+	}
+	return dbus.ObjectPath("/org/freedesktop/systemd1/unit/" + strings.ReplaceAll(name, ".", "_2e")), nil
+}
+
+func (manager *fakeManager) StartUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	id := manager.jobs.Add(1)
+	job := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/systemd1/job/%d", id))
+	result := "done"
+	if name == "broken.service" {
+		result = "failed"
+	}
+	go manager.conn.Emit("/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager.JobRemoved", id, job, name, result)
+	return job, nil
+}
+
+// fakeUnit serves unit properties, NRestarts only on service interface like systemd.
+type fakeUnit struct{}
+
+func (fakeUnit) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	switch {
+	case iface == "org.freedesktop.systemd1.Unit" && name == "ActiveState":
+		return dbus.MakeVariant("active"), nil
+	case iface == "org.freedesktop.systemd1.Service" && name == "NRestarts":
+		return dbus.MakeVariant(uint32(2)), nil
+	}
+	return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []any{"unknown property " + name})
+}
+
+// privateBus starts a private dbus-daemon serving fake systemd manager and returns its address.
+func privateBus(t *testing.T) string {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+
+	cmd := exec.Command(daemon, "--session", "--nofork", "--print-address", "--address=unix:path="+filepath.Join(t.TempDir(), "bus"))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	} else if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := strings.TrimSpace(line)
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	manager := &fakeManager{conn: conn}
+	if err := conn.Export(manager, "/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager"); err != nil {
+		t.Fatal(err)
+	} else if err := conn.Export(fakeUnit{}, "/org/freedesktop/systemd1/unit/app_2eservice", "org.freedesktop.DBus.Properties"); err != nil {
+		t.Fatal(err)
+	} else if reply, err := conn.RequestName("org.freedesktop.systemd1", dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatal("FAIL request name", reply, err)
+	}
+	return address
+}
+
+func TestDBusBackend(t *testing.T) {
+	backend, err := unix.NewDBusBackend(privateBus(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	if state, err := backend.Property(ctx, "app.service", "ActiveState"); err != nil || state != "active" {
+		t.Fatal("FAIL", state, err)
+	} else if restarts, err := backend.Property(ctx, "app.service", "NRestarts"); err != nil || restarts != "2" {
+		t.Fatal("FAIL", restarts, err)
+	} else if _, err := backend.Property(ctx, "missing.service", "ActiveState"); !errors.Is(err, unix.ErrUnitNotFound) {
+		t.Fatal("FAIL", err)
+	}
+
+	if err := backend.Start(ctx, "app.service"); err != nil {
+		t.Fatal("FAIL", err)
+	} else if err := backend.Start(ctx, "broken.service"); err == nil || err.Error() != "broken.service start job failed" {
+		t.Fatal("FAIL", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := backend.Start(canceled, "app.service"); !errors.Is(err, context.Canceled) {
+		t.Fatal("FAIL", err)
+	}
+}
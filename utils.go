@@ -1,34 +1,47 @@
 package unix
 
 import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"errors"
 	"fmt"
-	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
-// eOf handle execute error for exit code
-func eOf(err error) error {
-	if err == nil {
-		return nil
-	} else if exitErr, ok := err.(*exec.ExitError); ok {
-		return fmt.Errorf("%s", string(exitErr.Stderr))
-	} else {
-		return err
+// managedMarker marks files generated by this package.
+const managedMarker = "# managed by github.com/mekramy/unix"
+
+// crons get all cron jobs, missing crontab treated as empty.
+func crons(ctx context.Context) ([]string, error) {
+	out, err := output(ctx, privileged("crontab", "-l"))
+	if errors.Is(err, ErrCrontabMissing) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
+	return strings.Split(string(out), "\n"), nil
 }
 
-// evOf handle execute error for command with output
-func evOf[T any](v T, err error) (T, error) {
-	return v, eOf(err)
+// writeCrontab replaces crontab content and restarts cron daemon.
+func writeCrontab(ctx context.Context, content string) error {
+	cmd := privileged("crontab", "-")
+	cmd.Stdin = strings.NewReader(content)
+	if err := run(ctx, cmd); err != nil {
+		return err
+	}
+	return run(ctx, privileged("systemctl", "restart", "cron"))
 }
 
-// crons get all cron jobs
-func crons() ([]string, error) {
-	out, err := evOf(exec.Command("sudo", "crontab", "-l").Output())
-	if err != nil {
-		return nil, err
+// reloadNginx tests nginx configuration and reloads or restarts nginx.
+func reloadNginx(ctx context.Context, action string) error {
+	if err := run(ctx, privileged("nginx", "-t")); err != nil {
+		return fmt.Errorf("%w: %w", ErrNginxInvalid, err)
 	}
-	return strings.Split(string(out), "\n"), nil
+	return run(ctx, privileged("systemctl", action, "nginx"))
 }
 
 // cronCommand extracts the command from a cron expression.
@@ -52,3 +65,238 @@ func cronCommand(cronExpr string) (bool, string) {
 	}
 	return true, strings.Join(parts[5:], " ")
 }
+
+// nginxDirectives extracts arguments of each occurrence of a directive from an nginx config.
+// directives split on ; { and } so several directives on one line are found, quotes removed from arguments.
+func nginxDirectives(content, directive string) [][]string {
+	result := make([][]string, 0)
+	for _, fields := range nginxStatements(content) {
+		if len(fields) > 1 && fields[0] == directive {
+			result = append(result, fields[1:])
+		}
+	}
+	return result
+}
+
+// nginxStatements tokenizes nginx config into simple directives terminated by ;.
+func nginxStatements(content string) [][]string {
+	result := make([][]string, 0)
+	var fields []string
+	var token strings.Builder
+	var quote rune
+	quoted, comment, escaped := false, false, false
+	flush := func() {
+		if token.Len() > 0 || quoted {
+			fields = append(fields, token.String())
+		}
+		token.Reset()
+		quoted = false
+	}
+
+	for _, r := range content {
+		switch {
+		case comment:
+			comment = r != '\n'
+		case escaped:
+			if !strings.ContainsRune(`"'\\`, r) {
+				token.WriteRune('\\')
+			}
+			token.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			token.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, quoted = r, true
+		case r == '#':
+			flush()
+			comment = true
+		case r == ';':
+			flush()
+			if len(fields) > 0 {
+				result = append(result, fields)
+			}
+			fields = nil
+		case r == '{' || r == '}':
+			// block header (e.g. server, location /) or block end
+			flush()
+			fields = nil
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			token.WriteRune(r)
+		}
+	}
+	return result
+}
+
+// nginxDirective extracts all values of a directive from an nginx config.
+func nginxDirective(content, directive string) []string {
+	result := make([]string, 0)
+	for _, args := range nginxDirectives(content, directive) {
+		result = append(result, args...)
+	}
+	return result
+}
+
+// nginxDefaultListens extracts normalized listen addresses marked as default_server.
+func nginxDefaultListens(content string) []string {
+	result := make([]string, 0)
+	for _, args := range nginxDirectives(content, "listen") {
+		if slices.Contains(args[1:], "default_server") {
+			result = append(result, listenAddress(args[0]))
+		}
+	}
+	return result
+}
+
+// listenAddress normalizes nginx listen address, so 80, *:80 and 0.0.0.0:80 are equal.
+func listenAddress(address string) string {
+	address = strings.ToLower(address)
+	if strings.HasPrefix(address, "unix:") {
+		return address
+	}
+
+	host, port := address, "80"
+	if _, err := strconv.Atoi(address); err == nil {
+		host, port = "*", address
+	} else if strings.HasPrefix(address, "[") {
+		if end := strings.LastIndex(address, "]"); end >= 0 {
+			host = address[:end+1]
+			if rest := address[end+1:]; strings.HasPrefix(rest, ":") {
+				port = rest[1:]
+			}
+		}
+	} else if h, p, ok := strings.Cut(address, ":"); ok {
+		host, port = h, p
+	}
+
+	if host == "0.0.0.0" {
+		host = "*"
+	}
+	return host + ":" + port
+}
+
+// domainsOverlap checks if nginx server names could match the same host.
+// names compared case-insensitively, *.example.com, .example.com and www.* wildcards supported, regex names ignored.
+func domainsOverlap(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if a == "" || b == "" || a == "_" || b == "_" || strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+		return false
+	}
+	return a == b || domainMatch(a, b) || domainMatch(b, a)
+}
+
+// domainMatch checks if name matches nginx wildcard server name pattern.
+func domainMatch(pattern, name string) bool {
+	switch {
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(name, pattern[1:])
+	case strings.HasPrefix(pattern, "."):
+		return name == pattern[1:] || strings.HasSuffix(name, pattern)
+	case strings.HasSuffix(pattern, ".*"):
+		return strings.HasPrefix(name, pattern[:len(pattern)-1])
+	}
+	return false
+}
+
+// apr1 hashes password using Apache MD5 (apr1) algorithm supported by nginx auth_basic.
+func apr1(password string) (string, error) {
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	salt := make([]byte, len(random))
+	for i, b := range random {
+		salt[i] = itoa64[int(b)%len(itoa64)]
+	}
+	return apr1Salt(password, string(salt)), nil
+}
+
+// apr1Salt hashes password using Apache MD5 (apr1) algorithm with the salt.
+func apr1Salt(password, salt string) string {
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	const magic = "$apr1$"
+
+	pw := []byte(password)
+	alt := md5.Sum(append(append(append([]byte{}, pw...), salt...), pw...))
+	ctx := append(append(append([]byte{}, pw...), magic...), salt...)
+	for i := len(pw); i > 0; i -= 16 {
+		ctx = append(ctx, alt[:min(16, i)]...)
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 == 1 {
+			ctx = append(ctx, 0)
+		} else {
+			ctx = append(ctx, pw[0])
+		}
+	}
+
+	final := md5.Sum(ctx)
+	for i := 0; i < 1000; i++ {
+		round := make([]byte, 0)
+		if i&1 == 1 {
+			round = append(round, pw...)
+		} else {
+			round = append(round, final[:]...)
+		}
+		if i%3 != 0 {
+			round = append(round, salt...)
+		}
+		if i%7 != 0 {
+			round = append(round, pw...)
+		}
+		if i&1 == 1 {
+			round = append(round, final[:]...)
+		} else {
+			round = append(round, pw...)
+		}
+		final = md5.Sum(round)
+	}
+
+	var result strings.Builder
+	encode := func(v uint, n int) {
+		for ; n > 0; n-- {
+			result.WriteByte(itoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, g := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint(final[g[0]])<<16|uint(final[g[1]])<<8|uint(final[g[2]]), 4)
+	}
+	encode(uint(final[11]), 2)
+	return magic + salt + "$" + result.String()
+}
+
+// nginxQuote quotes value for nginx config if it contains special characters.
+func nginxQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\r\n;{}\"'\\#") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// systemdQuote quotes value for systemd unit setting.
+func systemdQuote(value string) string {
+	return `"` + strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"%", "%%",
+		"\n", `\n`,
+	).Replace(value) + `"`
+}
+
+// envQuote quotes value for systemd environment file.
+func envQuote(value string) string {
+	return `"` + strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"`", "\\`",
+		"$", `\$`,
+	).Replace(value) + `"`
+}